@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+// resolvePubKeys accepts hex public keys or addresses of our own wallets.
+func resolvePubKeys(keys string, wallets *wallet.Wallets) [][]byte {
+	var pubKeys [][]byte
+
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.TrimSpace(key)
+
+		if w, ok := wallets.Wallets[key]; ok {
+			pubKeys = append(pubKeys, w.PublicKey)
+			continue
+		}
+
+		pubKey, err := hex.DecodeString(key)
+		if err != nil {
+			core.Handle(core.ErrInvalidPublicKey)
+		}
+
+		_, err = wallet.ParsePublicKey(pubKey)
+		core.Handle(err)
+
+		pubKeys = append(pubKeys, pubKey)
+	}
+
+	return pubKeys
+}
+
+func (cli *CommandLine) createMultisig(m int, keys string) {
+	wallets, _ := wallet.CreateWallets()
+
+	redeemScript, err := script.MultisigScript(m, resolvePubKeys(keys, wallets))
+	core.Handle(err)
+
+	fmt.Printf("Address: %s\n", wallet.ScriptAddress(redeemScript))
+	fmt.Printf("Redeem script: %x\n", redeemScript)
+}
+
+func (cli *CommandLine) addMultisigAddress(m int, keys string) {
+	wallets, _ := wallet.CreateWallets()
+
+	redeemScript, err := script.MultisigScript(m, resolvePubKeys(keys, wallets))
+	core.Handle(err)
+
+	address := wallets.AddScript(redeemScript)
+	wallets.SaveFile()
+
+	fmt.Printf("multisig address added: %s\n", address)
+}
+
+func (cli *CommandLine) getPubKey(address string) {
+	wallets, err := wallet.CreateWallets()
+	core.Handle(err)
+
+	w, ok := wallets.Wallets[address]
+	if !ok {
+		core.Handle(core.ErrInvalidAddress)
+	}
+
+	fmt.Printf("%x\n", w.PublicKey)
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+func fileDigest(path string) []byte {
+	content, err := ioutil.ReadFile(path)
+	core.Handle(err)
+
+	digest := sha256.Sum256(content)
+
+	return digest[:]
+}
+
+func (cli *CommandLine) notarize(path, from string, fee int) {
+	if !wallet.ValidateAddress(from) {
+		core.Handle(core.ErrInvalidAddress)
+	}
+
+	digest := fileDigest(path)
+
+	chain := continueBlockchain(from)
+	defer chain.Database.Close()
+
+	payments := []factory.Payment{{Data: digest}}
+	tx := factory.NewTransaction(from, payments, fee, from, 0, factory.LargestFirst{}, chain)
+	cbTx := factory.CoinbaseTX(from, "", fee)
+	block := chain.AddBlock([]*factory.Transaction{cbTx, tx})
+
+	fmt.Printf("Digest: %x\n", digest)
+	fmt.Printf("Transaction: %x\n", tx.ID)
+	fmt.Printf("Block: %x\n", block.Hash)
+}
+
+func (cli *CommandLine) proveNotarization(path string) {
+	digest := fileDigest(path)
+
+	chain := continueBlockchain("")
+	defer chain.Database.Close()
+
+	block, tx, ok := chain.FindData(digest)
+	if !ok {
+		core.Handle(core.ErrDataNotFound)
+	}
+
+	proof, err := block.TransactionProof(tx.ID)
+	core.Handle(err)
+
+	pow := factory.NewProof(block)
+
+	fmt.Printf("Digest: %x\n", digest)
+	fmt.Printf("Transaction: %x\n", tx.ID)
+	fmt.Printf("Block: %x height: %d time: %d\n", block.Hash, block.Height, block.Timestamp)
+	fmt.Printf("Merkle root: %x\n", block.HashTransactions())
+	fmt.Printf("Merkle index: %d\n", proof.Index)
+	printMerklePath(proof)
+	fmt.Printf("Valid: %t\n", pow.Validate() && block.VerifyTransactionProof(tx, proof))
+}
@@ -1,28 +1,71 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/wilmacedo/willchain-go/config"
 	"github.com/wilmacedo/willchain-go/core"
 	"github.com/wilmacedo/willchain-go/factory"
-	"github.com/wilmacedo/willchain-go/utils"
 	"github.com/wilmacedo/willchain-go/wallet"
 )
 
 type CommandLine struct{}
 
 func (cli *CommandLine) printUsage() {
-	fmt.Println("Usage:")
+	fmt.Println("Usage: [-datadir DIR] [-network NETWORK] COMMAND")
+	fmt.Println(" -datadir [DIR] - Where chains and wallets are kept, $WILLCHAIN_DATADIR or ~/.willchain by default")
+	fmt.Println(" -network [NETWORK] - main (default), test or regtest, $WILLCHAIN_NETWORK too, each in its own subdirectory")
 	fmt.Println(" balance -address [ADDRESS] - Get the balance of address")
 	fmt.Println(" createblockchain -address [ADDRESS] - Creates a blockchain in another address")
 	fmt.Println(" printchain - Prints the blocks in the chain")
-	fmt.Println(" send -from [FROM] -to [TO] -amount [AMOUNT] - Send amount from to another account and specificy amount")
-	fmt.Println(" createwallet - Creates a new wallet")
+	fmt.Println(" send -from [FROM] -to [TO] -amount [AMOUNT] -fee [FEE] -strategy [STRATEGY] -inputs [TXID:INDEX,...] -newchange -locktime [LOCKTIME] - Send amount from to another account and specificy amount")
+	fmt.Println("   repeat -to and -amount to pay many recipients in one transaction")
+	fmt.Println("   strategies: largest (default), smallest, bnb, random; -inputs spends exactly the given outputs instead")
+	fmt.Println("   -newchange sends change to a fresh wallet address")
+	fmt.Println("   -locktime [HEIGHT|UNIXTIME] pre-signs a payment that can't be mined before then, it is printed for sendrawtransaction")
+	fmt.Println(" sendmany -from [FROM] -file [CSV] -fee [FEE] -strategy [STRATEGY] -inputs [TXID:INDEX,...] -newchange -locktime [LOCKTIME] - Pay every address,amount row of the file in one transaction")
+	fmt.Println(" listunspent -address [ADDRESS] - List the unspent outputs of address")
+	fmt.Println(" reindexutxo - Rebuilds the unspent outputs index from the chain")
+	fmt.Println(" migratechain - Upgrades a chain stored by an older release, which opening it for any command does too")
+	fmt.Println(" movelegacydata - Moves the chain and wallets that older releases kept in ./tmp into the main network of the data directory")
+	fmt.Println(" createrawtransaction -inputs [TXID:INDEX,...] -to [TO] -amount [AMOUNT] -locktime [LOCKTIME] -sequence [SEQUENCE] - Creates an unsigned transaction, -to and -amount can be repeated")
+	fmt.Println(" decoderawtransaction -hex [HEX] - Prints a serialized transaction as JSON")
+	fmt.Println(" signrawtransaction -hex [HEX] -sighash [TYPE] - Signs every input owned by our wallets")
+	fmt.Println("   sighash types: ALL (default), NONE, SINGLE, each optionally with |ANYONECANPAY so others can add inputs")
+	fmt.Println(" combinerawtransaction -hex [HEX,HEX,...] - Merges the inputs of transactions signed apart into the first one")
+	fmt.Println(" sendrawtransaction -hex [HEX] | -file [FILE] -miner [ADDRESS] - Validates the transaction and mines it in a new block")
+	fmt.Println(" createpsbt -inputs [TXID:INDEX,...] -to [TO] -amount [AMOUNT] -locktime [LOCKTIME] -sequence [SEQUENCE] -out [FILE] - Creates a partially signed transaction file for offline signing")
+	fmt.Println(" signpsbt -in [FILE] -out [FILE] -sighash [TYPE] -yes - Adds signatures from our wallets, no chain needed, multisig cosigners sign in turn")
+	fmt.Println("   the outputs and the fee are printed for confirmation first, -yes signs without asking")
+	fmt.Println(" combinepsbt -in [FILE,FILE,...] -out [FILE] - Merges the signatures of many partial transaction files")
+	fmt.Println(" finalizepsbt -in [FILE] -out [FILE] - Writes the signed transaction, ready for sendrawtransaction -file")
+	fmt.Println(" initiateswap -from [FROM] -to [TO] -amount [AMOUNT] -locktime [LOCKTIME] -secrethash [HASH] -fee [FEE] - Locks amount in a hash time locked contract for an atomic swap")
+	fmt.Println("   without -secrethash a new secret is created, the counterparty passes its hash to lock the other side of the swap")
+	fmt.Println(" redeemswap -contract [HEX] -outpoint [TXID:INDEX] -secret [SECRET] -to [ADDRESS] -fee [FEE] - Claims a contract revealing the secret")
+	fmt.Println(" refundswap -contract [HEX] -outpoint [TXID:INDEX] -to [ADDRESS] -fee [FEE] - Takes back the contract funds after its lock time")
+	fmt.Println(" auditswap -contract [HEX] -outpoint [TXID:INDEX] - Prints the contract terms and, with an outpoint, whether it is funded, redeemed or refunded")
+	fmt.Println(" notarize -file [FILE] -from [FROM] -fee [FEE] - Anchors the SHA-256 digest of the file in a data output")
+	fmt.Println(" notarize -file [FILE] -proof - Prints the block and merkle path that prove the file was anchored")
+	fmt.Println(" gettxproof -txid [TXID] - Prints a proof that the transaction is in a block, without the rest of the block")
+	fmt.Println(" verifytxproof -proof [HEX] - Checks a proof of gettxproof, no chain needed")
+	fmt.Println(" startnode -listen [HOST:PORT] - Serves block headers, transaction proofs and block filters to light clients")
+	fmt.Println(" spvsync -node [HOST:PORT] -checkpoint [HEIGHT:HASH] - Downloads and checks the block headers of a node, from the genesis or the checkpoint, no chain needed")
+	fmt.Println(" spvbalance -node [HOST:PORT] -address [ADDRESS,...] -checkpoint [HEIGHT:HASH] - Gets the balance of our wallet addresses, or the given ones, from proofs of a node")
+	fmt.Println(" spvscan -node [HOST:PORT] -address [ADDRESS,...] -checkpoint [HEIGHT:HASH] - Same as spvbalance, matching block filters locally so the node never sees our addresses")
+	fmt.Println(" spvbloom -node [HOST:PORT] -address [ADDRESS,...] -fprate [RATE] -checkpoint [HEIGHT:HASH] - Same as spvbalance, from merkle blocks matched against a bloom filter of our addresses")
+	fmt.Println(" createwallet -algorithm [ALGORITHM] - Creates a new wallet, ecdsa-p256 by default or ed25519")
 	fmt.Println(" listaddresses - List the addresses in our wallet file")
+	fmt.Println(" getpubkey -address [ADDRESS] - Prints the public key of one of our addresses")
+	fmt.Println(" createmultisig -m [M] -keys [KEY,...] - Prints the m-of-n multisig address of public keys or our addresses")
+	fmt.Println(" addmultisigaddress -m [M] -keys [KEY,...] - Same as createmultisig and keeps the address in our wallet file")
 }
 
 func (cli *CommandLine) validateArgs() {
@@ -33,7 +76,7 @@ func (cli *CommandLine) validateArgs() {
 }
 
 func (cli *CommandLine) printChain() {
-	chain := factory.ContinueBlockchain("")
+	chain := continueBlockchain("")
 	defer chain.Database.Close()
 
 	iter := chain.Iterator()
@@ -69,12 +112,13 @@ func (cli *CommandLine) getBalance(address string) {
 		core.Handle(core.ErrInvalidAddress)
 	}
 
-	chain := factory.ContinueBlockchain(address)
+	chain := continueBlockchain(address)
 	defer chain.Database.Close()
 
 	balance := 0
 
-	txs := chain.FindResTX(utils.DecodeAddress(address))
+	utxos := factory.UTXOSet{Blockchain: chain}
+	txs := utxos.FindResTX(factory.AddressScript(address))
 
 	for _, tx := range txs {
 		balance += tx.Value
@@ -83,28 +127,132 @@ func (cli *CommandLine) getBalance(address string) {
 	fmt.Printf("Balance of %s: %d\n", address, balance)
 }
 
-func (cli *CommandLine) send(from, to string, amount int) {
+func paysTo(tx *factory.Transaction, address string) bool {
+	lockingScript := factory.AddressScript(address)
+
+	for _, res := range tx.Results {
+		if bytes.Equal(res.LockingScript, lockingScript) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cli *CommandLine) send(from string, payments []factory.Payment, fee int, strategy string, inputs []factory.Outpoint, newChange bool, lockTime int64) {
+	var selector factory.CoinSelector
+
 	if !wallet.ValidateAddress(from) {
 		core.Handle(core.ErrInvalidAddress)
 	}
 
-	if !wallet.ValidateAddress(to) {
-		core.Handle(core.ErrInvalidAddress)
+	if len(inputs) > 0 {
+		selector = factory.ManualSelection{Outpoints: inputs}
+	} else {
+		var err error
+
+		selector, err = factory.NewCoinSelector(strategy)
+		core.Handle(err)
+	}
+
+	var wallets *wallet.Wallets
+
+	// The change wallet is only saved once the transaction pays it, so a
+	// failed send doesn't leave it behind.
+	change := from
+	if newChange {
+		var err error
+
+		wallets, err = wallet.CreateWallets()
+		core.Handle(err)
+
+		change, err = wallets.AddWallet(wallet.DefaultAlgorithm)
+		core.Handle(err)
 	}
 
-	chain := factory.ContinueBlockchain(from)
+	chain := continueBlockchain(from)
 	defer chain.Database.Close()
 
-	tx := factory.NewTransaction(from, to, amount, chain)
-	cbTx := factory.CoinbaseTX(from, "")
+	tx := factory.NewTransaction(from, payments, fee, change, lockTime, selector, chain)
+
+	if newChange && paysTo(tx, change) {
+		wallets.SaveFile()
+
+		fmt.Printf("Change address: %s\n", change)
+	}
+
+	if !tx.IsFinal(chain.Height()+1, time.Now().Unix()) {
+		fmt.Printf("Transaction is locked until %d, send it later with sendrawtransaction:\n", lockTime)
+		fmt.Println(hex.EncodeToString(tx.Serialize()))
+		return
+	}
+
+	cbTx := factory.CoinbaseTX(from, "", fee)
 	chain.AddBlock([]*factory.Transaction{cbTx, tx})
 
 	fmt.Println("Success!")
 }
 
-func (cli *CommandLine) createWallet() {
+func (cli *CommandLine) listUnspent(address string) {
+	if !wallet.ValidateAddress(address) {
+		core.Handle(core.ErrInvalidAddress)
+	}
+
+	chain := continueBlockchain(address)
+	defer chain.Database.Close()
+
+	utxos := factory.UTXOSet{Blockchain: chain}
+
+	for _, unspent := range utxos.FindUnspentResults(factory.AddressScript(address)) {
+		fmt.Printf("%x:%d value: %d confirmations: %d\n", unspent.TxID, unspent.Index, unspent.Result.Value, unspent.Confirmations)
+	}
+}
+
+// continueBlockchain opens the chain and reports what opening it upgraded.
+func continueBlockchain(address string) *factory.Blockchain {
+	chain := factory.ContinueBlockchain(address)
+
+	for _, upgrade := range chain.Upgrades {
+		fmt.Println(upgrade)
+	}
+
+	return chain
+}
+
+func (cli *CommandLine) reindexUTXO() {
+	chain := continueBlockchain("")
+	defer chain.Database.Close()
+
+	utxos := factory.UTXOSet{Blockchain: chain}
+	utxos.Reindex()
+
+	fmt.Println("Finished!")
+}
+
+// migrateChain is kept from before opening a chain upgraded it.
+func (cli *CommandLine) migrateChain() {
+	chain := continueBlockchain("")
+	defer chain.Database.Close()
+
+	fmt.Printf("Database is at version %d\n", factory.SchemaVersion)
+}
+
+func (cli *CommandLine) moveLegacyData() {
+	moved, err := config.MoveLegacyData()
+	core.Handle(err)
+
+	for _, path := range moved {
+		fmt.Printf("Moved the data of ./tmp to %s\n", path)
+	}
+}
+
+func (cli *CommandLine) createWallet(algorithmName string) {
+	algorithm, err := wallet.ParseAlgorithm(algorithmName)
+	core.Handle(err)
+
 	wallets, _ := wallet.CreateWallets()
-	address := wallets.AddWallet()
+	address, err := wallets.AddWallet(algorithm)
+	core.Handle(err)
 	wallets.SaveFile()
 
 	fmt.Printf("wallet created: %v\n", address)
@@ -125,45 +273,311 @@ func (cli *CommandLine) listAddresses() {
 	}
 }
 
+// parseOptions reads the options given before the command and returns the
+// command with its arguments.
+func (cli *CommandLine) parseOptions() []string {
+	options := flag.NewFlagSet("willchain", flag.ExitOnError)
+	dataDir := options.String("datadir", "", "Directory of the chains and wallets")
+	network := options.String("network", "", "Network: main, test or regtest")
+
+	err := options.Parse(os.Args[1:])
+	core.Handle(err)
+
+	if options.NArg() == 0 {
+		cli.printUsage()
+		runtime.Goexit()
+	}
+
+	if *dataDir != "" {
+		config.SetDataDir(*dataDir)
+	}
+
+	if *network != "" {
+		err = config.SetNetwork(*network)
+	} else {
+		_, err = config.Network()
+	}
+	core.Handle(err)
+
+	if options.Arg(0) != "movelegacydata" && config.FindLegacyData() {
+		fmt.Printf("Found the chain and wallets of an older release in ./tmp, run movelegacydata to move them to %s\n", config.NetworkDir())
+	}
+
+	return options.Args()
+}
+
 func (cli *CommandLine) Run() {
 	cli.validateArgs()
+	args := cli.parseOptions()
 
 	balanceCmd := flag.NewFlagSet("balance", flag.ExitOnError)
 	createBlockchainCmd := flag.NewFlagSet("createblockchain", flag.ExitOnError)
 	sendCmd := flag.NewFlagSet("send", flag.ExitOnError)
+	sendManyCmd := flag.NewFlagSet("sendmany", flag.ExitOnError)
 	printChainCmd := flag.NewFlagSet("printchain", flag.ExitOnError)
 	createWalletCmd := flag.NewFlagSet("createwallet", flag.ExitOnError)
 	listAddressesCmd := flag.NewFlagSet("listaddresses", flag.ExitOnError)
+	listUnspentCmd := flag.NewFlagSet("listunspent", flag.ExitOnError)
+	reindexUTXOCmd := flag.NewFlagSet("reindexutxo", flag.ExitOnError)
+	migrateChainCmd := flag.NewFlagSet("migratechain", flag.ExitOnError)
+	moveLegacyDataCmd := flag.NewFlagSet("movelegacydata", flag.ExitOnError)
+	createRawCmd := flag.NewFlagSet("createrawtransaction", flag.ExitOnError)
+	decodeRawCmd := flag.NewFlagSet("decoderawtransaction", flag.ExitOnError)
+	signRawCmd := flag.NewFlagSet("signrawtransaction", flag.ExitOnError)
+	combineRawCmd := flag.NewFlagSet("combinerawtransaction", flag.ExitOnError)
+	sendRawCmd := flag.NewFlagSet("sendrawtransaction", flag.ExitOnError)
+	createPSBTCmd := flag.NewFlagSet("createpsbt", flag.ExitOnError)
+	signPSBTCmd := flag.NewFlagSet("signpsbt", flag.ExitOnError)
+	combinePSBTCmd := flag.NewFlagSet("combinepsbt", flag.ExitOnError)
+	finalizePSBTCmd := flag.NewFlagSet("finalizepsbt", flag.ExitOnError)
+	getPubKeyCmd := flag.NewFlagSet("getpubkey", flag.ExitOnError)
+	createMultisigCmd := flag.NewFlagSet("createmultisig", flag.ExitOnError)
+	addMultisigCmd := flag.NewFlagSet("addmultisigaddress", flag.ExitOnError)
+	initiateSwapCmd := flag.NewFlagSet("initiateswap", flag.ExitOnError)
+	redeemSwapCmd := flag.NewFlagSet("redeemswap", flag.ExitOnError)
+	refundSwapCmd := flag.NewFlagSet("refundswap", flag.ExitOnError)
+	auditSwapCmd := flag.NewFlagSet("auditswap", flag.ExitOnError)
+	notarizeCmd := flag.NewFlagSet("notarize", flag.ExitOnError)
+	getTxProofCmd := flag.NewFlagSet("gettxproof", flag.ExitOnError)
+	verifyTxProofCmd := flag.NewFlagSet("verifytxproof", flag.ExitOnError)
+	startNodeCmd := flag.NewFlagSet("startnode", flag.ExitOnError)
+	spvSyncCmd := flag.NewFlagSet("spvsync", flag.ExitOnError)
+	spvBalanceCmd := flag.NewFlagSet("spvbalance", flag.ExitOnError)
+	spvScanCmd := flag.NewFlagSet("spvscan", flag.ExitOnError)
+	spvBloomCmd := flag.NewFlagSet("spvbloom", flag.ExitOnError)
 
 	balanceAddress := balanceCmd.String("address", "", "The address to retrieve balance")
 	createBlockchainAddress := createBlockchainCmd.String("address", "", "The address to be create")
 	sendFrom := sendCmd.String("from", "", "Source wallet address")
-	sendTo := sendCmd.String("to", "", "Destination wallet address")
-	sendAmount := sendCmd.Int("amount", 0, "Amount to send")
-
-	switch os.Args[1] {
+	sendTo := stringList{}
+	sendCmd.Var(&sendTo, "to", "Destination wallet address, can be repeated")
+	sendAmount := intList{}
+	sendCmd.Var(&sendAmount, "amount", "Amount to send, one per destination")
+	sendFee := sendCmd.Int("fee", 0, "Fee paid to the miner")
+	sendStrategy := sendCmd.String("strategy", factory.StrategyLargestFirst, "Coin selection strategy")
+	sendInputs := sendCmd.String("inputs", "", "Comma separated txid:index outputs to spend")
+	sendNewChange := sendCmd.Bool("newchange", false, "Send change to a fresh wallet address")
+	sendLockTime := sendCmd.Int64("locktime", 0, "Block height or unix time before which the transaction can't be mined")
+	sendManyFrom := sendManyCmd.String("from", "", "Source wallet address")
+	sendManyFile := sendManyCmd.String("file", "", "CSV file with address,amount rows")
+	sendManyFee := sendManyCmd.Int("fee", 0, "Fee paid to the miner")
+	sendManyStrategy := sendManyCmd.String("strategy", factory.StrategyLargestFirst, "Coin selection strategy")
+	sendManyInputs := sendManyCmd.String("inputs", "", "Comma separated txid:index outputs to spend")
+	sendManyNewChange := sendManyCmd.Bool("newchange", false, "Send change to a fresh wallet address")
+	sendManyLockTime := sendManyCmd.Int64("locktime", 0, "Block height or unix time before which the transaction can't be mined")
+	createWalletAlgorithm := createWalletCmd.String("algorithm", wallet.DefaultAlgorithm.String(), "Signature algorithm of the wallet key: ecdsa-p256 or ed25519")
+	listUnspentAddress := listUnspentCmd.String("address", "", "The address to list unspent outputs")
+	createRawInputs := createRawCmd.String("inputs", "", "Comma separated txid:index outputs to spend")
+	createRawTo := stringList{}
+	createRawCmd.Var(&createRawTo, "to", "Destination wallet address, can be repeated")
+	createRawAmount := intList{}
+	createRawCmd.Var(&createRawAmount, "amount", "Amount to send, one per destination")
+	createRawLockTime := createRawCmd.Int64("locktime", 0, "Block height or unix time before which the transaction can't be mined")
+	createRawSequence := createRawCmd.Uint("sequence", 0, "Sequence of every input, below 2^31 it is a relative lock")
+	decodeRawHex := decodeRawCmd.String("hex", "", "Serialized transaction")
+	signRawHex := signRawCmd.String("hex", "", "Serialized transaction")
+	signRawSigHash := signRawCmd.String("sighash", "ALL", "Signature hash type: ALL, NONE or SINGLE, optionally with |ANYONECANPAY")
+	combineRawHex := combineRawCmd.String("hex", "", "Comma separated serialized transactions")
+	sendRawHex := sendRawCmd.String("hex", "", "Serialized transaction")
+	sendRawFile := sendRawCmd.String("file", "", "File with the serialized transaction")
+	sendRawMiner := sendRawCmd.String("miner", "", "Address that receives the block reward and fee")
+	createPSBTInputs := createPSBTCmd.String("inputs", "", "Comma separated txid:index outputs to spend")
+	createPSBTTo := stringList{}
+	createPSBTCmd.Var(&createPSBTTo, "to", "Destination wallet address, can be repeated")
+	createPSBTAmount := intList{}
+	createPSBTCmd.Var(&createPSBTAmount, "amount", "Amount to send, one per destination")
+	createPSBTOut := createPSBTCmd.String("out", "", "Partial transaction file to write")
+	createPSBTLockTime := createPSBTCmd.Int64("locktime", 0, "Block height or unix time before which the transaction can't be mined")
+	createPSBTSequence := createPSBTCmd.Uint("sequence", 0, "Sequence of every input, below 2^31 it is a relative lock")
+	signPSBTIn := signPSBTCmd.String("in", "", "Partial transaction file to sign")
+	signPSBTOut := signPSBTCmd.String("out", "", "Partial transaction file to write")
+	signPSBTSigHash := signPSBTCmd.String("sighash", "ALL", "Signature hash type: ALL, NONE or SINGLE, optionally with |ANYONECANPAY")
+	signPSBTYes := signPSBTCmd.Bool("yes", false, "Sign without asking to confirm the outputs and the fee")
+	combinePSBTIn := combinePSBTCmd.String("in", "", "Comma separated partial transaction files")
+	combinePSBTOut := combinePSBTCmd.String("out", "", "Partial transaction file to write")
+	finalizePSBTIn := finalizePSBTCmd.String("in", "", "Partial transaction file to finalize")
+	finalizePSBTOut := finalizePSBTCmd.String("out", "", "Transaction file to write")
+	getPubKeyAddress := getPubKeyCmd.String("address", "", "Address of our wallet")
+	createMultisigM := createMultisigCmd.Int("m", 0, "Required signatures")
+	createMultisigKeys := createMultisigCmd.String("keys", "", "Comma separated public keys or addresses")
+	addMultisigM := addMultisigCmd.Int("m", 0, "Required signatures")
+	addMultisigKeys := addMultisigCmd.String("keys", "", "Comma separated public keys or addresses")
+	initiateSwapFrom := initiateSwapCmd.String("from", "", "Address that funds the contract and gets the refund")
+	initiateSwapTo := initiateSwapCmd.String("to", "", "Address that can redeem the contract")
+	initiateSwapAmount := initiateSwapCmd.Int("amount", 0, "Amount locked in the contract")
+	initiateSwapLockTime := initiateSwapCmd.Int64("locktime", 0, "Block height or unix time after which the contract can be refunded")
+	initiateSwapSecretHash := initiateSwapCmd.String("secrethash", "", "Secret hash of the counterparty contract")
+	initiateSwapFee := initiateSwapCmd.Int("fee", 0, "Fee paid to the miner")
+	redeemSwapContract := redeemSwapCmd.String("contract", "", "Contract script")
+	redeemSwapOutpoint := redeemSwapCmd.String("outpoint", "", "txid:index of the contract output")
+	redeemSwapSecret := redeemSwapCmd.String("secret", "", "Preimage of the contract secret hash")
+	redeemSwapTo := redeemSwapCmd.String("to", "", "Address that receives the funds, the contract recipient by default")
+	redeemSwapFee := redeemSwapCmd.Int("fee", 0, "Fee paid to the miner")
+	refundSwapContract := refundSwapCmd.String("contract", "", "Contract script")
+	refundSwapOutpoint := refundSwapCmd.String("outpoint", "", "txid:index of the contract output")
+	refundSwapTo := refundSwapCmd.String("to", "", "Address that receives the funds, the contract refund address by default")
+	refundSwapFee := refundSwapCmd.Int("fee", 0, "Fee paid to the miner")
+	auditSwapContract := auditSwapCmd.String("contract", "", "Contract script")
+	auditSwapOutpoint := auditSwapCmd.String("outpoint", "", "txid:index of the contract output")
+	notarizeFile := notarizeCmd.String("file", "", "File to notarize")
+	notarizeFrom := notarizeCmd.String("from", "", "Wallet address paying the fee")
+	notarizeFee := notarizeCmd.Int("fee", 0, "Fee paid to the miner")
+	notarizeProof := notarizeCmd.Bool("proof", false, "Prove the file was notarized instead")
+	getTxProofTxID := getTxProofCmd.String("txid", "", "Transaction to prove")
+	verifyTxProofProof := verifyTxProofCmd.String("proof", "", "Serialized proof")
+	startNodeListen := startNodeCmd.String("listen", "localhost:3000", "Address to listen on")
+	spvSyncNode := spvSyncCmd.String("node", "localhost:3000", "Node to sync from")
+	spvBalanceNode := spvBalanceCmd.String("node", "localhost:3000", "Node to ask for proofs")
+	spvBalanceAddress := spvBalanceCmd.String("address", "", "Addresses to track, comma separated, our wallet addresses by default")
+	spvScanNode := spvScanCmd.String("node", "localhost:3000", "Node to ask for filters and blocks")
+	spvScanAddress := spvScanCmd.String("address", "", "Addresses to track, comma separated, our wallet addresses by default")
+	spvBloomNode := spvBloomCmd.String("node", "localhost:3000", "Node to ask for merkle blocks")
+	spvBloomAddress := spvBloomCmd.String("address", "", "Addresses to track, comma separated, our wallet addresses by default")
+	spvBloomRate := spvBloomCmd.Float64("fprate", 0.0001, "False positive rate of the bloom filter, higher hides our addresses better")
+	spvSyncCheckpoint := spvSyncCmd.String("checkpoint", "", "Header to start from instead of the genesis when none is synced, as printed by startnode")
+	spvBalanceCheckpoint := spvBalanceCmd.String("checkpoint", "", "Header to start from instead of the genesis when none is synced, as printed by startnode")
+	spvScanCheckpoint := spvScanCmd.String("checkpoint", "", "Header to start from instead of the genesis when none is synced, as printed by startnode")
+	spvBloomCheckpoint := spvBloomCmd.String("checkpoint", "", "Header to start from instead of the genesis when none is synced, as printed by startnode")
+
+	switch args[0] {
 	case "balance":
-		err := balanceCmd.Parse(os.Args[2:])
+		err := balanceCmd.Parse(args[1:])
 		core.Handle(err)
 
 	case "createblockchain":
-		err := createBlockchainCmd.Parse(os.Args[2:])
+		err := createBlockchainCmd.Parse(args[1:])
 		core.Handle(err)
 
 	case "send":
-		err := sendCmd.Parse(os.Args[2:])
+		err := sendCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "sendmany":
+		err := sendManyCmd.Parse(args[1:])
 		core.Handle(err)
 
 	case "printchain":
-		err := printChainCmd.Parse(os.Args[2:])
+		err := printChainCmd.Parse(args[1:])
 		core.Handle(err)
 
 	case "createwallet":
-		err := createWalletCmd.Parse(os.Args[2:])
+		err := createWalletCmd.Parse(args[1:])
 		core.Handle(err)
 
 	case "listaddresses":
-		err := listAddressesCmd.Parse(os.Args[2:])
+		err := listAddressesCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "listunspent":
+		err := listUnspentCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "reindexutxo":
+		err := reindexUTXOCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "migratechain":
+		err := migrateChainCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "movelegacydata":
+		err := moveLegacyDataCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "createrawtransaction":
+		err := createRawCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "decoderawtransaction":
+		err := decodeRawCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "signrawtransaction":
+		err := signRawCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "combinerawtransaction":
+		err := combineRawCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "sendrawtransaction":
+		err := sendRawCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "createpsbt":
+		err := createPSBTCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "signpsbt":
+		err := signPSBTCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "combinepsbt":
+		err := combinePSBTCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "finalizepsbt":
+		err := finalizePSBTCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "getpubkey":
+		err := getPubKeyCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "createmultisig":
+		err := createMultisigCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "addmultisigaddress":
+		err := addMultisigCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "initiateswap":
+		err := initiateSwapCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "redeemswap":
+		err := redeemSwapCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "refundswap":
+		err := refundSwapCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "auditswap":
+		err := auditSwapCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "notarize":
+		err := notarizeCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "gettxproof":
+		err := getTxProofCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "verifytxproof":
+		err := verifyTxProofCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "startnode":
+		err := startNodeCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "spvsync":
+		err := spvSyncCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "spvbalance":
+		err := spvBalanceCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "spvscan":
+		err := spvScanCmd.Parse(args[1:])
+		core.Handle(err)
+
+	case "spvbloom":
+		err := spvBloomCmd.Parse(args[1:])
 		core.Handle(err)
 
 	default:
@@ -190,12 +604,33 @@ func (cli *CommandLine) Run() {
 	}
 
 	if sendCmd.Parsed() {
-		if *sendFrom == "" || *sendTo == "" || *sendAmount <= 0 {
+		if *sendFrom == "" || len(sendTo) == 0 || *sendFee < 0 {
 			sendCmd.Usage()
 			runtime.Goexit()
 		}
 
-		cli.send(*sendFrom, *sendTo, *sendAmount)
+		payments, err := pairPayments(sendTo, sendAmount)
+		core.Handle(err)
+
+		inputs, err := parseOutpoints(*sendInputs)
+		core.Handle(err)
+
+		cli.send(*sendFrom, payments, *sendFee, *sendStrategy, inputs, *sendNewChange, *sendLockTime)
+	}
+
+	if sendManyCmd.Parsed() {
+		if *sendManyFrom == "" || *sendManyFile == "" || *sendManyFee < 0 {
+			sendManyCmd.Usage()
+			runtime.Goexit()
+		}
+
+		payments, err := readPayments(*sendManyFile)
+		core.Handle(err)
+
+		inputs, err := parseOutpoints(*sendManyInputs)
+		core.Handle(err)
+
+		cli.send(*sendManyFrom, payments, *sendManyFee, *sendManyStrategy, inputs, *sendManyNewChange, *sendManyLockTime)
 	}
 
 	if printChainCmd.Parsed() {
@@ -203,10 +638,248 @@ func (cli *CommandLine) Run() {
 	}
 
 	if createWalletCmd.Parsed() {
-		cli.createWallet()
+		cli.createWallet(*createWalletAlgorithm)
 	}
 
 	if listAddressesCmd.Parsed() {
 		cli.listAddresses()
 	}
+
+	if listUnspentCmd.Parsed() {
+		if *listUnspentAddress == "" {
+			listUnspentCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.listUnspent(*listUnspentAddress)
+	}
+
+	if reindexUTXOCmd.Parsed() {
+		cli.reindexUTXO()
+	}
+
+	if migrateChainCmd.Parsed() {
+		cli.migrateChain()
+	}
+
+	if moveLegacyDataCmd.Parsed() {
+		cli.moveLegacyData()
+	}
+
+	if createRawCmd.Parsed() {
+		if *createRawInputs == "" || len(createRawTo) == 0 {
+			createRawCmd.Usage()
+			runtime.Goexit()
+		}
+
+		inputs, err := parseOutpoints(*createRawInputs)
+		core.Handle(err)
+
+		payments, err := pairPayments(createRawTo, createRawAmount)
+		core.Handle(err)
+
+		cli.createRawTransaction(inputs, payments, *createRawLockTime, uint32(*createRawSequence))
+	}
+
+	if decodeRawCmd.Parsed() {
+		if *decodeRawHex == "" {
+			decodeRawCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.decodeRawTransaction(*decodeRawHex)
+	}
+
+	if signRawCmd.Parsed() {
+		if *signRawHex == "" {
+			signRawCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.signRawTransaction(*signRawHex, *signRawSigHash)
+	}
+
+	if combineRawCmd.Parsed() {
+		if *combineRawHex == "" {
+			combineRawCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.combineRawTransactions(strings.Split(*combineRawHex, ","))
+	}
+
+	if sendRawCmd.Parsed() {
+		if (*sendRawHex == "") == (*sendRawFile == "") || *sendRawMiner == "" {
+			sendRawCmd.Usage()
+			runtime.Goexit()
+		}
+
+		rawHex := *sendRawHex
+		if *sendRawFile != "" {
+			rawHex = readRawTransactionFile(*sendRawFile)
+		}
+
+		cli.sendRawTransaction(rawHex, *sendRawMiner)
+	}
+
+	if createPSBTCmd.Parsed() {
+		if *createPSBTInputs == "" || len(createPSBTTo) == 0 || *createPSBTOut == "" {
+			createPSBTCmd.Usage()
+			runtime.Goexit()
+		}
+
+		inputs, err := parseOutpoints(*createPSBTInputs)
+		core.Handle(err)
+
+		payments, err := pairPayments(createPSBTTo, createPSBTAmount)
+		core.Handle(err)
+
+		cli.createPartialTransaction(inputs, payments, *createPSBTOut, *createPSBTLockTime, uint32(*createPSBTSequence))
+	}
+
+	if signPSBTCmd.Parsed() {
+		if *signPSBTIn == "" || *signPSBTOut == "" {
+			signPSBTCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.signPartialTransaction(*signPSBTIn, *signPSBTOut, *signPSBTSigHash, *signPSBTYes)
+	}
+
+	if combinePSBTCmd.Parsed() {
+		if *combinePSBTIn == "" || *combinePSBTOut == "" {
+			combinePSBTCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.combinePartialTransactions(strings.Split(*combinePSBTIn, ","), *combinePSBTOut)
+	}
+
+	if finalizePSBTCmd.Parsed() {
+		if *finalizePSBTIn == "" || *finalizePSBTOut == "" {
+			finalizePSBTCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.finalizePartialTransaction(*finalizePSBTIn, *finalizePSBTOut)
+	}
+
+	if getPubKeyCmd.Parsed() {
+		if *getPubKeyAddress == "" {
+			getPubKeyCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.getPubKey(*getPubKeyAddress)
+	}
+
+	if createMultisigCmd.Parsed() {
+		if *createMultisigM <= 0 || *createMultisigKeys == "" {
+			createMultisigCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.createMultisig(*createMultisigM, *createMultisigKeys)
+	}
+
+	if addMultisigCmd.Parsed() {
+		if *addMultisigM <= 0 || *addMultisigKeys == "" {
+			addMultisigCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.addMultisigAddress(*addMultisigM, *addMultisigKeys)
+	}
+
+	if initiateSwapCmd.Parsed() {
+		if *initiateSwapFrom == "" || *initiateSwapTo == "" || *initiateSwapAmount <= 0 || *initiateSwapLockTime <= 0 {
+			initiateSwapCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.initiateSwap(*initiateSwapFrom, *initiateSwapTo, *initiateSwapAmount, *initiateSwapFee, *initiateSwapLockTime, *initiateSwapSecretHash)
+	}
+
+	if redeemSwapCmd.Parsed() {
+		if *redeemSwapContract == "" || *redeemSwapOutpoint == "" || *redeemSwapSecret == "" {
+			redeemSwapCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.spendSwap(*redeemSwapContract, *redeemSwapOutpoint, *redeemSwapSecret, *redeemSwapTo, *redeemSwapFee)
+	}
+
+	if refundSwapCmd.Parsed() {
+		if *refundSwapContract == "" || *refundSwapOutpoint == "" {
+			refundSwapCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.spendSwap(*refundSwapContract, *refundSwapOutpoint, "", *refundSwapTo, *refundSwapFee)
+	}
+
+	if auditSwapCmd.Parsed() {
+		if *auditSwapContract == "" {
+			auditSwapCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.auditSwap(*auditSwapContract, *auditSwapOutpoint)
+	}
+
+	if notarizeCmd.Parsed() {
+		if *notarizeFile == "" || (*notarizeFrom == "" && !*notarizeProof) || *notarizeFee < 0 {
+			notarizeCmd.Usage()
+			runtime.Goexit()
+		}
+
+		if *notarizeProof {
+			cli.proveNotarization(*notarizeFile)
+		} else {
+			cli.notarize(*notarizeFile, *notarizeFrom, *notarizeFee)
+		}
+	}
+
+	if getTxProofCmd.Parsed() {
+		if *getTxProofTxID == "" {
+			getTxProofCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.getTxProof(*getTxProofTxID)
+	}
+
+	if verifyTxProofCmd.Parsed() {
+		if *verifyTxProofProof == "" {
+			verifyTxProofCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.verifyTxProof(*verifyTxProofProof)
+	}
+
+	if startNodeCmd.Parsed() {
+		cli.startNode(*startNodeListen)
+	}
+
+	if spvSyncCmd.Parsed() {
+		cli.spvSync(*spvSyncNode, *spvSyncCheckpoint)
+	}
+
+	if spvBalanceCmd.Parsed() {
+		cli.spvBalance(*spvBalanceNode, *spvBalanceAddress, *spvBalanceCheckpoint)
+	}
+
+	if spvScanCmd.Parsed() {
+		cli.spvScan(*spvScanNode, *spvScanAddress, *spvScanCheckpoint)
+	}
+
+	if spvBloomCmd.Parsed() {
+		if *spvBloomRate <= 0 || *spvBloomRate >= 1 {
+			spvBloomCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cli.spvBloom(*spvBloomNode, *spvBloomAddress, *spvBloomRate, *spvBloomCheckpoint)
+	}
 }
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+
+	return nil
+}
+
+type intList []int
+
+func (list *intList) String() string {
+	var values []string
+
+	for _, value := range *list {
+		values = append(values, strconv.Itoa(value))
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (list *intList) Set(value string) error {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+
+	*list = append(*list, number)
+
+	return nil
+}
+
+func pairPayments(addresses []string, amounts []int) ([]factory.Payment, error) {
+	var payments []factory.Payment
+
+	if len(addresses) != len(amounts) {
+		return nil, core.ErrPaymentsMismatch
+	}
+
+	for i, address := range addresses {
+		if !wallet.ValidateAddress(address) {
+			return nil, core.ErrInvalidAddress
+		}
+
+		payments = append(payments, factory.Payment{
+			Address: address,
+			Amount:  amounts[i],
+		})
+	}
+
+	return payments, nil
+}
+
+// readPayments loads "address,amount" rows from a CSV file. A first row whose
+// amount column is not a number is treated as a header and skipped.
+func readPayments(path string) ([]factory.Payment, error) {
+	var addresses []string
+	var amounts []int
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	for line := 0; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		amount, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			if line == 0 {
+				continue
+			}
+
+			return nil, err
+		}
+
+		addresses = append(addresses, strings.TrimSpace(record[0]))
+		amounts = append(amounts, amount)
+	}
+
+	return pairPayments(addresses, amounts)
+}
+
+func parseOutpoints(value string) ([]factory.Outpoint, error) {
+	var outpoints []factory.Outpoint
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		outpoint, err := factory.ParseOutpoint(item)
+		if err != nil {
+			return nil, err
+		}
+
+		outpoints = append(outpoints, outpoint)
+	}
+
+	return outpoints, nil
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/factory/merkle"
+	"github.com/wilmacedo/willchain-go/storage"
+)
+
+func printMerklePath(proof *merkle.Proof) {
+	for i, left := range proof.Path() {
+		side := "right"
+		if left {
+			side = "left"
+		}
+
+		fmt.Printf("Merkle path: %s %x\n", side, proof.Siblings[i])
+	}
+}
+
+func (cli *CommandLine) getTxProof(txIDHex string) {
+	txID, err := hex.DecodeString(txIDHex)
+	core.Handle(err)
+
+	chain := continueBlockchain("")
+	defer chain.Database.Close()
+
+	txProof, err := chain.TxProof(txID)
+	core.Handle(err)
+
+	fmt.Println(hex.EncodeToString(txProof.Serialize()))
+}
+
+// verifyTxProof needs no chain, with one it also tells if the block of the
+// proof is part of it.
+func (cli *CommandLine) verifyTxProof(proofHex string) {
+	data, err := hex.DecodeString(proofHex)
+	core.Handle(err)
+
+	txProof, err := factory.DeserializeTxProof(data)
+	core.Handle(err)
+
+	fmt.Printf("Transaction: %x\n", txProof.Transaction.ID)
+	fmt.Printf("Block: %x height: %d time: %d\n", txProof.Header.Block.Hash, txProof.Header.Block.Height, txProof.Header.Block.Timestamp)
+	fmt.Printf("Merkle root: %x\n", txProof.Header.MerkleRoot)
+	fmt.Printf("Merkle index: %d\n", txProof.Proof.Index)
+	printMerklePath(txProof.Proof)
+
+	if storage.Exists() {
+		chain := continueBlockchain("")
+		defer chain.Database.Close()
+
+		fmt.Printf("In chain: %t\n", txProof.InChain(chain))
+	}
+
+	fmt.Printf("Valid: %t\n", txProof.Verify())
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+func readPartialTransaction(path string) *factory.PartialTransaction {
+	content, err := ioutil.ReadFile(path)
+	core.Handle(err)
+
+	ptx, err := factory.DeserializePartialTransaction(content)
+	core.Handle(err)
+
+	return ptx
+}
+
+func writePartialTransaction(path string, ptx *factory.PartialTransaction) {
+	err := ioutil.WriteFile(path, ptx.Serialize(), 0644)
+	core.Handle(err)
+}
+
+func (cli *CommandLine) createPartialTransaction(inputs []factory.Outpoint, payments []factory.Payment, out string, lockTime int64, sequence uint32) {
+	tx := factory.NewRawTransaction(inputs, payments, lockTime, sequence)
+
+	wallets, err := wallet.CreateWallets()
+	core.Handle(err)
+
+	chain := continueBlockchain("")
+	defer chain.Database.Close()
+
+	ptx, err := chain.NewPartialTransaction(tx, wallets)
+	core.Handle(err)
+
+	writePartialTransaction(out, ptx)
+
+	fmt.Printf("Partial transaction written to %s\n", out)
+}
+
+// describeLockingScript names who a result pays: one of our addresses, a
+// script hash address, or else the script itself.
+func describeLockingScript(lockingScript []byte, wallets *wallet.Wallets) string {
+	if pubKeyHash, ok := script.ExtractPubKeyHash(lockingScript); ok {
+		if w, ok := wallets.FindByPubKeyHash(pubKeyHash); ok {
+			return fmt.Sprintf("%s (ours)", w.Address())
+		}
+	}
+
+	if scriptHash, ok := script.ExtractScriptHash(lockingScript); ok {
+		address := wallet.EncodeAddress(wallet.ScriptHashVersion, scriptHash)
+		if _, ok := wallets.FindScriptByHash(scriptHash); ok {
+			return fmt.Sprintf("%s (ours)", address)
+		}
+
+		return string(address)
+	}
+
+	return script.Disassemble(lockingScript)
+}
+
+// printPartialTransaction shows what signing agrees to. The values spent
+// come from the previous transactions carried in the file, checked against
+// the IDs the requests spend.
+func printPartialTransaction(ptx *factory.PartialTransaction, wallets *wallet.Wallets) {
+	for reqId, input := range ptx.Inputs {
+		req := ptx.Transaction.Requests[reqId]
+		fmt.Printf("Input %d: %d from %x:%d\n", reqId, input.PrevResult.Value, req.ID, req.Out)
+
+		// Transactions migrated from the legacy format keep their old ID,
+		// which can't be worked out again from their content.
+		if input.PrevTransaction.Version == factory.LegacyVersion {
+			fmt.Printf("   its value can't be checked, %x was migrated from the legacy format\n", req.ID)
+		}
+	}
+
+	for resId, res := range ptx.Transaction.Results {
+		fmt.Printf("Output %d: %d to %s\n", resId, res.Value, describeLockingScript(res.LockingScript, wallets))
+	}
+
+	fmt.Printf("Fee: %d\n", ptx.Fee())
+}
+
+// confirm asks a yes or no question on the terminal, no by default.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		core.Handle(err)
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+func (cli *CommandLine) signPartialTransaction(in, out, sigHash string, yes bool) {
+	ptx := readPartialTransaction(in)
+
+	hashType, err := factory.ParseSigHashType(sigHash)
+	core.Handle(err)
+
+	wallets, err := wallet.CreateWallets()
+	core.Handle(err)
+
+	printPartialTransaction(ptx, wallets)
+
+	if !yes && !confirm("Sign this transaction?") {
+		fmt.Println("Nothing was signed")
+		runtime.Goexit()
+	}
+
+	signed := 0
+	for _, address := range wallets.GetAllAddresses() {
+		count, err := ptx.Sign(wallets.GetWallet(address), hashType)
+		core.Handle(err)
+
+		signed += count
+	}
+
+	writePartialTransaction(out, ptx)
+
+	fmt.Printf("Signed %d inputs, written to %s\n", signed, out)
+}
+
+func (cli *CommandLine) combinePartialTransactions(in []string, out string) {
+	ptx := readPartialTransaction(in[0])
+
+	for _, path := range in[1:] {
+		err := ptx.Combine(readPartialTransaction(path))
+		core.Handle(err)
+	}
+
+	writePartialTransaction(out, ptx)
+
+	fmt.Printf("Combined %d files into %s\n", len(in), out)
+}
+
+func (cli *CommandLine) finalizePartialTransaction(in, out string) {
+	ptx := readPartialTransaction(in)
+
+	tx, err := ptx.Finalize()
+	core.Handle(err)
+
+	err = ioutil.WriteFile(out, []byte(hex.EncodeToString(tx.Serialize())), 0644)
+	core.Handle(err)
+
+	fmt.Printf("Transaction %x written to %s\n", tx.ID, out)
+}
+
+func readRawTransactionFile(path string) string {
+	content, err := ioutil.ReadFile(path)
+	core.Handle(err)
+
+	return strings.TrimSpace(string(content))
+}
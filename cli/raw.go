@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+func decodeRawTransaction(rawHex string) *factory.Transaction {
+	data, err := hex.DecodeString(rawHex)
+	core.Handle(err)
+
+	tx, err := factory.DeserializeTransaction(data)
+	core.Handle(err)
+
+	return tx
+}
+
+func (cli *CommandLine) createRawTransaction(inputs []factory.Outpoint, payments []factory.Payment, lockTime int64, sequence uint32) {
+	tx := factory.NewRawTransaction(inputs, payments, lockTime, sequence)
+
+	fmt.Println(hex.EncodeToString(tx.Serialize()))
+}
+
+func (cli *CommandLine) decodeRawTransaction(rawHex string) {
+	tx := decodeRawTransaction(rawHex)
+
+	content, err := json.MarshalIndent(tx, "", "  ")
+	core.Handle(err)
+
+	fmt.Println(string(content))
+}
+
+func (cli *CommandLine) signRawTransaction(rawHex, sigHash string) {
+	tx := decodeRawTransaction(rawHex)
+
+	hashType, err := factory.ParseSigHashType(sigHash)
+	core.Handle(err)
+
+	wallets, err := wallet.CreateWallets()
+	core.Handle(err)
+
+	chain := continueBlockchain("")
+	defer chain.Database.Close()
+
+	complete, err := chain.SignWithWallets(tx, wallets, hashType)
+	core.Handle(err)
+
+	fmt.Println(hex.EncodeToString(tx.Serialize()))
+	fmt.Printf("Complete: %t\n", complete)
+}
+
+func (cli *CommandLine) combineRawTransactions(rawHexes []string) {
+	var txs []*factory.Transaction
+
+	for _, rawHex := range rawHexes {
+		txs = append(txs, decodeRawTransaction(rawHex))
+	}
+
+	tx := factory.CombineRawTransactions(txs)
+
+	fmt.Println(hex.EncodeToString(tx.Serialize()))
+}
+
+func (cli *CommandLine) sendRawTransaction(rawHex, miner string) {
+	if !wallet.ValidateAddress(miner) {
+		core.Handle(core.ErrInvalidAddress)
+	}
+
+	tx := decodeRawTransaction(rawHex)
+
+	chain := continueBlockchain("")
+	defer chain.Database.Close()
+
+	fee, err := chain.ValidateTransaction(tx)
+	core.Handle(err)
+
+	cbTx := factory.CoinbaseTX(miner, "", fee)
+	chain.AddBlock([]*factory.Transaction{cbTx, tx})
+
+	fmt.Printf("Transaction %x mined\n", tx.ID)
+}
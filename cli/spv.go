@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/network"
+	"github.com/wilmacedo/willchain-go/spv"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+func (cli *CommandLine) startNode(address string) {
+	chain := continueBlockchain("")
+	defer chain.Database.Close()
+
+	if checkpoint, legacy := lightCheckpoint(chain); legacy && checkpoint == nil {
+		fmt.Println("Every block was migrated from the legacy format, light clients can't sync until a new block is mined")
+	} else if legacy {
+		fmt.Printf("Blocks up to height %d were migrated from the legacy format, light clients have to sync with -checkpoint %s\n", checkpoint.Height-1, checkpoint)
+	}
+
+	fmt.Printf("Serving headers, proofs and filters on %s\n", address)
+	core.Handle(network.NewServer(chain).ListenAndServe(address))
+}
+
+// lightCheckpoint returns the lowest block above those migrated from the
+// legacy format, whose headers light clients can't verify, and whether there
+// are any.
+func lightCheckpoint(chain *factory.Blockchain) (*spv.Checkpoint, bool) {
+	var checkpoint *spv.Checkpoint
+
+	iter := chain.Iterator()
+	for {
+		block := iter.Next()
+
+		if block.Version == factory.LegacyVersion {
+			return checkpoint, true
+		}
+
+		if len(block.PreviousHash) == 0 {
+			return nil, false
+		}
+
+		checkpoint = &spv.Checkpoint{Height: block.Height, Hash: block.Hash}
+	}
+}
+
+func syncHeaders(peer *network.Peer, checkpoint string) *spv.HeaderChain {
+	headers, err := spv.LoadHeaderChain()
+	core.Handle(err)
+
+	if checkpoint != "" {
+		headers.Checkpoint, err = spv.ParseCheckpoint(checkpoint)
+		core.Handle(err)
+	}
+
+	connected, err := headers.Sync(peer)
+	core.Handle(headers.Save())
+
+	if errors.Is(err, core.ErrLegacyHeader) {
+		fmt.Println("The chain of the node starts with blocks migrated from the legacy format, which light clients can't verify.")
+		fmt.Println("Sync again with the -checkpoint startnode printed on the node.")
+		runtime.Goexit()
+	}
+	core.Handle(err)
+
+	tip := headers.Tip()
+	fmt.Printf("Synced %d headers, tip %x height: %d\n", connected, tip.Block.Hash, tip.Block.Height)
+
+	if headers.Start() > 0 {
+		fmt.Printf("Synced from the checkpoint at height %d, results paid and spent below it are not seen\n", headers.Start())
+	}
+
+	return headers
+}
+
+func (cli *CommandLine) spvSync(node, checkpoint string) {
+	peer, err := network.Dial(node)
+	core.Handle(err)
+	defer peer.Close()
+
+	syncHeaders(peer, checkpoint)
+}
+
+// trackedScripts returns the given addresses, or every address and redeem
+// script of our wallet file, with their locking scripts.
+func trackedScripts(addresses string) ([]string, [][]byte) {
+	var tracked []string
+	var lockingScripts [][]byte
+
+	if addresses != "" {
+		for _, address := range strings.Split(addresses, ",") {
+			tracked = append(tracked, strings.TrimSpace(address))
+		}
+	} else {
+		wallets, err := wallet.CreateWallets()
+		core.Handle(err)
+
+		tracked = wallets.GetAllAddresses()
+		for address := range wallets.Scripts {
+			tracked = append(tracked, address)
+		}
+	}
+
+	for _, address := range tracked {
+		if !wallet.ValidateAddress(address) {
+			core.Handle(core.ErrInvalidAddress)
+		}
+
+		lockingScripts = append(lockingScripts, factory.AddressScript(address))
+	}
+
+	return tracked, lockingScripts
+}
+
+func printCoins(coins []spv.Coin, tracked []string, lockingScripts [][]byte) {
+	balances := make(map[string]int)
+	for _, coin := range coins {
+		balances[string(coin.Result.LockingScript)] += coin.Result.Value
+		fmt.Printf("%x:%d value: %d confirmations: %d\n", coin.Outpoint.TxID, coin.Outpoint.Index, coin.Result.Value, coin.Confirmations)
+	}
+
+	for i, address := range tracked {
+		fmt.Printf("Balance of %s: %d\n", address, balances[string(lockingScripts[i])])
+	}
+}
+
+func (cli *CommandLine) spvBalance(node, addresses, checkpoint string) {
+	tracked, lockingScripts := trackedScripts(addresses)
+
+	peer, err := network.Dial(node)
+	core.Handle(err)
+	defer peer.Close()
+
+	headers := syncHeaders(peer, checkpoint)
+
+	proofs, err := peer.GetProofs(lockingScripts)
+	core.Handle(err)
+
+	coins, err := headers.Coins(proofs, lockingScripts)
+	core.Handle(err)
+
+	printCoins(coins, tracked, lockingScripts)
+}
+
+// spvScan works out the balance like spvBalance but never sends our scripts
+// to the node: it matches block filters locally and fetches only the blocks
+// that match.
+func (cli *CommandLine) spvScan(node, addresses, checkpoint string) {
+	tracked, lockingScripts := trackedScripts(addresses)
+
+	peer, err := network.Dial(node)
+	core.Handle(err)
+	defer peer.Close()
+
+	headers := syncHeaders(peer, checkpoint)
+
+	_, err = headers.SyncFilterHeaders(peer)
+	core.Handle(headers.Save())
+	core.Handle(err)
+
+	proofs, fetched, err := headers.Scan(peer, lockingScripts)
+	core.Handle(err)
+
+	fmt.Printf("Fetched %d of %d blocks\n", len(fetched), len(headers.FilterHeaders))
+
+	coins, err := headers.Coins(proofs, lockingScripts)
+	core.Handle(err)
+
+	printCoins(coins, tracked, lockingScripts)
+}
+
+// spvBloom works out the balance from merkle blocks: the node matches blocks
+// against a bloom filter of our addresses, noisy enough to hide which ones
+// are ours.
+func (cli *CommandLine) spvBloom(node, addresses string, falsePositiveRate float64, checkpoint string) {
+	tracked, lockingScripts := trackedScripts(addresses)
+
+	filter, err := spv.NewBloomFilter(lockingScripts, falsePositiveRate)
+	core.Handle(err)
+
+	peer, err := network.Dial(node)
+	core.Handle(err)
+	defer peer.Close()
+
+	headers := syncHeaders(peer, checkpoint)
+
+	core.Handle(peer.LoadFilter(filter))
+
+	merkleBlocks, err := headers.MerkleBlocks(peer)
+	core.Handle(err)
+
+	matched := 0
+	for _, merkleBlock := range merkleBlocks {
+		matched += len(merkleBlock.Transactions)
+	}
+
+	fmt.Printf("Matched %d transactions in %d blocks\n", matched, len(merkleBlocks))
+
+	coins, err := headers.MerkleCoins(merkleBlocks, lockingScripts)
+	core.Handle(err)
+
+	printCoins(coins, tracked, lockingScripts)
+}
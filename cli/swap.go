@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+func decodeContract(contractHex string) ([]byte, script.AtomicSwap) {
+	contract, err := hex.DecodeString(contractHex)
+	core.Handle(err)
+
+	swap, ok := script.ExtractAtomicSwap(contract)
+	if !ok {
+		core.Handle(core.ErrNotAtomicSwap)
+	}
+
+	return contract, swap
+}
+
+func (cli *CommandLine) initiateSwap(from, to string, amount, fee int, lockTime int64, secretHashHex string) {
+	var secret, secretHash []byte
+
+	if !wallet.ValidateAddress(from) || !wallet.ValidateAddress(to) {
+		core.Handle(core.ErrInvalidAddress)
+	}
+
+	if secretHashHex == "" {
+		secret = make([]byte, script.SecretSize)
+		_, err := rand.Read(secret)
+		core.Handle(err)
+
+		hash := sha256.Sum256(secret)
+		secretHash = hash[:]
+	} else {
+		var err error
+
+		secretHash, err = hex.DecodeString(secretHashHex)
+		core.Handle(err)
+	}
+
+	contract, err := factory.NewAtomicSwapContract(to, from, secretHash, lockTime)
+	core.Handle(err)
+
+	contractAddress := string(wallet.ScriptAddress(contract))
+
+	chain := continueBlockchain(from)
+	defer chain.Database.Close()
+
+	payments := []factory.Payment{{Address: contractAddress, Amount: amount}}
+	tx := factory.NewTransaction(from, payments, fee, from, 0, factory.LargestFirst{}, chain)
+	cbTx := factory.CoinbaseTX(from, "", fee)
+	chain.AddBlock([]*factory.Transaction{cbTx, tx})
+
+	if secret != nil {
+		fmt.Printf("Secret: %x\n", secret)
+	}
+	fmt.Printf("Secret hash: %x\n", secretHash)
+	fmt.Printf("Contract: %x\n", contract)
+	fmt.Printf("Contract address: %s\n", contractAddress)
+	fmt.Printf("Contract output: %x:0\n", tx.ID)
+}
+
+// spendSwap redeems the contract output when a secret is given and refunds
+// it otherwise. A refund that is still locked is printed for a later
+// sendrawtransaction.
+func (cli *CommandLine) spendSwap(contractHex, outpoint, secretHex, to string, fee int) {
+	var secret []byte
+
+	contract, swap := decodeContract(contractHex)
+
+	input, err := factory.ParseOutpoint(outpoint)
+	core.Handle(err)
+
+	party := swap.RefundHash
+	if secretHex != "" {
+		secret, err = hex.DecodeString(secretHex)
+		core.Handle(err)
+
+		party = swap.RecipientHash
+	}
+
+	wallets, err := wallet.CreateWallets()
+	core.Handle(err)
+
+	w, ok := wallets.FindByPubKeyHash(party)
+	if !ok {
+		core.Handle(core.ErrNotContractParty)
+	}
+
+	if to == "" {
+		to = string(w.Address())
+	}
+
+	if !wallet.ValidateAddress(to) {
+		core.Handle(core.ErrInvalidAddress)
+	}
+
+	chain := continueBlockchain("")
+	defer chain.Database.Close()
+
+	tx, err := chain.SpendAtomicSwap(input, contract, secret, to, fee, w)
+	core.Handle(err)
+
+	if !tx.IsFinal(chain.Height()+1, time.Now().Unix()) {
+		fmt.Printf("Refund is locked until %d, send it later with sendrawtransaction:\n", swap.LockTime)
+		fmt.Println(hex.EncodeToString(tx.Serialize()))
+		return
+	}
+
+	fee, err = chain.ValidateTransaction(tx)
+	core.Handle(err)
+
+	cbTx := factory.CoinbaseTX(to, "", fee)
+	chain.AddBlock([]*factory.Transaction{cbTx, tx})
+
+	fmt.Printf("Transaction %x mined\n", tx.ID)
+}
+
+func (cli *CommandLine) auditSwap(contractHex, outpoint string) {
+	contract, swap := decodeContract(contractHex)
+
+	fmt.Printf("Contract address: %s\n", wallet.ScriptAddress(contract))
+	fmt.Printf("Recipient: %s\n", wallet.EncodeAddress(wallet.PubKeyHashVersion, swap.RecipientHash))
+	fmt.Printf("Refund: %s\n", wallet.EncodeAddress(wallet.PubKeyHashVersion, swap.RefundHash))
+	fmt.Printf("Secret hash: %x\n", swap.SecretHash)
+
+	if swap.LockTime >= factory.LockTimeThreshold {
+		fmt.Printf("Lock time: %s\n", time.Unix(swap.LockTime, 0).UTC())
+	} else {
+		fmt.Printf("Lock time: block %d\n", swap.LockTime)
+	}
+
+	if outpoint == "" {
+		return
+	}
+
+	input, err := factory.ParseOutpoint(outpoint)
+	core.Handle(err)
+
+	chain := continueBlockchain("")
+	defer chain.Database.Close()
+
+	utxos := factory.UTXOSet{Blockchain: chain}
+
+	if unspent, ok := utxos.FindResult(input.TxID, input.Index); ok {
+		if !unspent.Result.IsLockedWith(script.PayToScriptHash(script.Hash160(contract))) {
+			core.Handle(core.ErrContractMismatch)
+		}
+
+		refundable := factory.LockTimeReached(swap.LockTime, chain.Height()+1, time.Now().Unix())
+
+		fmt.Printf("Funded: %d confirmations: %d\n", unspent.Result.Value, unspent.Confirmations)
+		fmt.Printf("Refundable: %t\n", refundable)
+		return
+	}
+
+	tx, reqId, ok := chain.FindSpender(input)
+	if !ok {
+		fmt.Println("Contract output not found")
+		return
+	}
+
+	if secret, ok := script.ExtractAtomicSwapSecret(tx.Requests[reqId].UnlockingScript); ok {
+		fmt.Printf("Redeemed by %x\n", tx.ID)
+		fmt.Printf("Secret: %x\n", secret)
+		return
+	}
+
+	fmt.Printf("Refunded by %x\n", tx.ID)
+}
@@ -0,0 +1,209 @@
+// Package config resolves where the node keeps its data: a data directory
+// with one subdirectory per network, so chains and wallets of different
+// networks never mix.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+const (
+	DataDirEnv = "WILLCHAIN_DATADIR"
+	NetworkEnv = "WILLCHAIN_NETWORK"
+
+	MainNetwork    = "main"
+	TestNetwork    = "test"
+	RegtestNetwork = "regtest"
+
+	defaultDataDir = ".willchain"
+
+	// legacyDir is where releases before the data directory kept the chain
+	// and the wallets, relative to the working directory.
+	legacyDir = "tmp"
+
+	// walletFile is the name the wallet package keeps its file under.
+	walletFile = "wallets.data"
+
+	// Data directories hold private keys, only their owner gets in.
+	dirMode = 0700
+)
+
+var (
+	dataDir string
+	network string
+
+	legacyFiles = []string{"blocks", walletFile}
+)
+
+// SetDataDir overrides the data directory of the environment.
+func SetDataDir(dir string) {
+	dataDir = dir
+}
+
+// SetNetwork overrides the network of the environment.
+func SetNetwork(name string) error {
+	if !validNetwork(name) {
+		return core.ErrUnknownNetwork
+	}
+
+	network = name
+
+	return nil
+}
+
+func validNetwork(name string) bool {
+	return name == MainNetwork || name == TestNetwork || name == RegtestNetwork
+}
+
+// DataDir is the directory set with SetDataDir, then the one of
+// WILLCHAIN_DATADIR, then ~/.willchain.
+func DataDir() string {
+	if dataDir != "" {
+		return dataDir
+	}
+
+	if dir := os.Getenv(DataDirEnv); dir != "" {
+		return dir
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return defaultDataDir
+	}
+
+	return filepath.Join(home, defaultDataDir)
+}
+
+// Network is the network set with SetNetwork, then the one of
+// WILLCHAIN_NETWORK, then main.
+func Network() (string, error) {
+	if network != "" {
+		return network, nil
+	}
+
+	name := os.Getenv(NetworkEnv)
+	if name == "" {
+		return MainNetwork, nil
+	}
+
+	if !validNetwork(name) {
+		return "", core.ErrUnknownNetwork
+	}
+
+	return name, nil
+}
+
+// NetworkDir is the subdirectory of the data directory for the network.
+func NetworkDir() string {
+	name, err := Network()
+	core.Handle(err)
+
+	return filepath.Join(DataDir(), name)
+}
+
+// Path is the path of a file or directory of the network.
+func Path(name string) string {
+	return filepath.Join(NetworkDir(), name)
+}
+
+// EnsureDir creates the network directory, and the data directory above it,
+// readable only by their owner. Directories created by older releases, or by
+// hand, are made private too.
+func EnsureDir() error {
+	if err := os.MkdirAll(NetworkDir(), dirMode); err != nil {
+		return err
+	}
+
+	for _, dir := range []string{DataDir(), NetworkDir()} {
+		if err := os.Chmod(dir, dirMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindLegacyData tells whether ./tmp holds the chain and the wallets of an
+// older release that MoveLegacyData would move.
+func FindLegacyData() bool {
+	return checkLegacyData() == nil
+}
+
+// checkLegacyData returns why MoveLegacyData can't move ./tmp, if it can't.
+func checkLegacyData() error {
+	if name, err := Network(); err != nil {
+		return err
+	} else if name != MainNetwork {
+		return core.ErrLegacyNetwork
+	}
+
+	found := 0
+	for _, name := range legacyFiles {
+		if _, err := os.Stat(filepath.Join(legacyDir, name)); err == nil {
+			found++
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if found == 0 {
+		return core.ErrNoLegacyData
+	} else if found < len(legacyFiles) {
+		return core.ErrPartialLegacyData
+	}
+
+	for _, name := range legacyFiles {
+		if _, err := os.Stat(Path(name)); !os.IsNotExist(err) {
+			return core.ErrDataDirInUse
+		}
+	}
+
+	return nil
+}
+
+// MoveLegacyData moves the chain and the wallets of ./tmp, where they were
+// kept before the data directory, into the directory of the main network.
+// They are moved together or not at all, and only into a network directory
+// holding neither. It returns the paths it moved to.
+func MoveLegacyData() ([]string, error) {
+	var moved []string
+
+	if err := checkLegacyData(); err != nil {
+		return nil, err
+	}
+
+	if err := EnsureDir(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range legacyFiles {
+		if err := os.Rename(filepath.Join(legacyDir, name), Path(name)); err != nil {
+			return nil, restoreLegacyData(moved, err)
+		}
+
+		moved = append(moved, Path(name))
+	}
+
+	// Releases before the data directory left the wallets readable by all.
+	if err := os.Chmod(Path(walletFile), 0600); err != nil {
+		return moved, err
+	}
+
+	return moved, nil
+}
+
+// restoreLegacyData moves what MoveLegacyData already moved back to ./tmp,
+// so that a failed move leaves both where they were.
+func restoreLegacyData(moved []string, err error) error {
+	for _, path := range moved {
+		if restoreErr := os.Rename(path, filepath.Join(legacyDir, filepath.Base(path))); restoreErr != nil {
+			return fmt.Errorf("%w, and moving %s back failed: %v", err, path, restoreErr)
+		}
+	}
+
+	return err
+}
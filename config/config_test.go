@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// useTestDirs points the data directory and the working directory, where
+// ./tmp is looked for, at new temporary directories.
+func useTestDirs(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	data := filepath.Join(dir, "data")
+
+	if err := os.Mkdir(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		dataDir, network = "", ""
+	})
+
+	SetDataDir(data)
+
+	return work, data
+}
+
+func writeTestFile(t *testing.T, path string, mode os.FileMode) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(path), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fileMode(t *testing.T, path string) os.FileMode {
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return info.Mode().Perm()
+}
+
+func TestEnsureDir(t *testing.T) {
+	_, data := useTestDirs(t)
+
+	if err := EnsureDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{data, NetworkDir()} {
+		if mode := fileMode(t, dir); mode != dirMode {
+			t.Errorf("%s: mode %o", dir, mode)
+		}
+	}
+
+	// Directories left open by older releases are closed.
+	for _, dir := range []string{data, NetworkDir()} {
+		if err := os.Chmod(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := EnsureDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{data, NetworkDir()} {
+		if mode := fileMode(t, dir); mode != dirMode {
+			t.Errorf("%s: mode %o after another run", dir, mode)
+		}
+	}
+}
+
+func TestMoveLegacyData(t *testing.T) {
+	work, _ := useTestDirs(t)
+
+	writeTestFile(t, filepath.Join(work, legacyDir, "blocks", "000001.sst"), 0644)
+	writeTestFile(t, filepath.Join(work, legacyDir, walletFile), 0644)
+
+	if !FindLegacyData() {
+		t.Fatal("legacy data not found")
+	}
+
+	moved, err := MoveLegacyData()
+	if err != nil || len(moved) != len(legacyFiles) {
+		t.Fatalf("moved %q, %v", moved, err)
+	}
+
+	if _, err := os.Stat(Path(filepath.Join("blocks", "000001.sst"))); err != nil {
+		t.Errorf("blocks: %v", err)
+	}
+
+	if mode := fileMode(t, Path(walletFile)); mode != 0600 {
+		t.Errorf("wallets mode %o", mode)
+	}
+
+	if mode := fileMode(t, NetworkDir()); mode != dirMode {
+		t.Errorf("network directory mode %o", mode)
+	}
+
+	if FindLegacyData() {
+		t.Error("legacy data found after the move")
+	}
+
+	if _, err := MoveLegacyData(); !errors.Is(err, core.ErrNoLegacyData) {
+		t.Errorf("second move: %v", err)
+	}
+}
+
+func TestMoveLegacyDataRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		legacy  []string
+		current []string
+		network string
+		err     error
+	}{
+		{"nothing to move", nil, nil, MainNetwork, core.ErrNoLegacyData},
+		{"chain only", []string{"blocks"}, nil, MainNetwork, core.ErrPartialLegacyData},
+		{"wallets only", []string{walletFile}, nil, MainNetwork, core.ErrPartialLegacyData},
+		{"chain in the way", legacyFiles, []string{"blocks"}, MainNetwork, core.ErrDataDirInUse},
+		{"wallets in the way", legacyFiles, []string{walletFile}, MainNetwork, core.ErrDataDirInUse},
+		{"other network", legacyFiles, nil, TestNetwork, core.ErrLegacyNetwork},
+	}
+
+	for _, test := range tests {
+		work, _ := useTestDirs(t)
+
+		if err := SetNetwork(test.network); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range test.legacy {
+			writeTestFile(t, filepath.Join(work, legacyDir, name), 0644)
+		}
+
+		for _, name := range test.current {
+			writeTestFile(t, Path(name), 0600)
+		}
+
+		if FindLegacyData() {
+			t.Errorf("%s: legacy data found", test.name)
+		}
+
+		if moved, err := MoveLegacyData(); !errors.Is(err, test.err) || len(moved) != 0 {
+			t.Errorf("%s: moved %q, %v, want %v", test.name, moved, err, test.err)
+		}
+
+		// Nothing was moved apart.
+		for _, name := range test.legacy {
+			if _, err := os.Stat(filepath.Join(work, legacyDir, name)); err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+		}
+	}
+}
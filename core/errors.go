@@ -7,3 +7,78 @@ var ErrEnoughFunds = errors.New("not enough funds")
 
 var ErrNilPreviousTransactions = errors.New("previous transactions doest not exist")
 var ErrNilTransaction = errors.New("transaction doest not exist")
+var ErrUnknownStrategy = errors.New("coin selection strategy does not exist")
+var ErrNoPayments = errors.New("transaction must have at least one payment")
+var ErrInvalidAmount = errors.New("amount must be greater than zero")
+var ErrInvalidFee = errors.New("fee can not be negative")
+var ErrPaymentsMismatch = errors.New("each recipient needs exactly one amount")
+var ErrInvalidOutpoint = errors.New("outpoint must be formatted as txid:index")
+var ErrDuplicateInput = errors.New("input is used more than once")
+var ErrUnknownInput = errors.New("input is spent or does not belong to the wallet")
+var ErrNoInputs = errors.New("transaction must have at least one input")
+var ErrUnexpectedCoinbase = errors.New("coinbase transaction can only be created by a block")
+var ErrInvalidTransactionID = errors.New("transaction id does not match its content")
+var ErrMissingInput = errors.New("input is spent or does not exist")
+var ErrPartialMismatch = errors.New("partial transactions do not spend the same transaction")
+var ErrMissingSignature = errors.New("partial transaction is missing a valid signature")
+
+var ErrScriptTooLarge = errors.New("script is too large")
+var ErrMalformedScript = errors.New("script push exceeds its length")
+var ErrNotPushOnly = errors.New("unlocking script can only push data")
+var ErrScriptFailed = errors.New("script evaluated to false")
+var ErrElementTooLarge = errors.New("script element is too large")
+var ErrTooManyOperations = errors.New("script has too many operations")
+var ErrUnbalancedConditional = errors.New("script conditional is not balanced")
+var ErrUnknownOpcode = errors.New("script opcode is not supported")
+var ErrStackUnderflow = errors.New("script stack has not enough items")
+var ErrNumberOverflow = errors.New("script number is too large")
+var ErrNonMinimalNumber = errors.New("script number is not minimally encoded")
+var ErrInvalidMultisig = errors.New("multisig key or signature count is not valid")
+var ErrLockTimeNotReached = errors.New("lock time has not been reached")
+var ErrTransactionNotFinal = errors.New("transaction lock time has not been reached")
+var ErrSequenceLockNotMet = errors.New("relative lock time of the input has not been reached")
+var ErrNotAtomicSwap = errors.New("script is not an atomic swap contract")
+var ErrInvalidSecret = errors.New("secret does not match the contract secret hash")
+var ErrContractMismatch = errors.New("output is not locked to the contract")
+var ErrNotContractParty = errors.New("wallet can't spend this contract")
+var ErrDataTooLarge = errors.New("data output is too large")
+var ErrInvalidDataOutput = errors.New("data output must carry a single push and no value")
+var ErrLeafNotFound = errors.New("merkle leaf is not in the tree")
+var ErrUnknownSigHashType = errors.New("signature hash type is not valid")
+var ErrSigHashSingle = errors.New("SIGHASH_SINGLE request has no matching result")
+var ErrMalformedEncoding = errors.New("encoded data is malformed")
+var ErrUnknownVersion = errors.New("encoding version is not supported")
+var ErrInvalidBlock = errors.New("block is not valid")
+var ErrLegacyFormat = errors.New("block uses the legacy gob format")
+var ErrDataNotFound = errors.New("data is not anchored in the chain")
+var ErrUnknownRedeemScript = errors.New("redeem script of the input is not in the wallet")
+var ErrInvalidPublicKey = errors.New("public key is not valid")
+var ErrNonCanonicalSignature = errors.New("signature is not canonically encoded")
+var ErrUnknownAlgorithm = errors.New("signature algorithm is not supported")
+var ErrInvalidPrivateKey = errors.New("private key is not valid")
+var ErrInvalidMessage = errors.New("network message is malformed")
+var ErrUnexpectedMessage = errors.New("peer answered with an unexpected message")
+var ErrUnknownCommand = errors.New("network command is not supported")
+var ErrRejected = errors.New("peer rejected the request")
+var ErrHeaderNotConnected = errors.New("header does not extend the header chain")
+var ErrInvalidProof = errors.New("merkle proof does not match a synced header")
+var ErrInvalidFilter = errors.New("block filter does not match its filter header")
+var ErrInvalidBloomFilter = errors.New("bloom filter is too large or malformed")
+var ErrNoBloomFilter = errors.New("no bloom filter is loaded")
+var ErrKeyNotFound = errors.New("key is not in the database")
+var ErrForeignBatch = errors.New("batch was created by another database")
+var ErrDatabaseClosed = errors.New("database is closed")
+var ErrUnknownNetwork = errors.New("network must be main, test or regtest")
+var ErrCorruptDatabase = errors.New("blockchain database is corrupted, restore a backup or create the chain again")
+var ErrNewerSchema = errors.New("blockchain database was written by a newer release")
+var ErrDoubleSpend = errors.New("input is spent by another transaction of the block")
+var ErrInvalidCoinbase = errors.New("coinbase pays more than the block reward and fees")
+var ErrReorgTooDeep = errors.New("peer branch forks deeper than the reorganization limit")
+var ErrForkNotLonger = errors.New("peer is on a branch that is not longer than ours")
+var ErrLegacyHeader = errors.New("header of a block migrated from the legacy format can't be verified, sync from a checkpoint after it")
+var ErrInvalidCheckpoint = errors.New("checkpoint must be formatted as height:hash")
+var ErrLegacyNetwork = errors.New("data of older releases belongs to the main network")
+var ErrDataDirInUse = errors.New("network directory already holds a chain or wallets")
+var ErrNoLegacyData = errors.New("no chain or wallets of an older release in ./tmp")
+var ErrPartialLegacyData = errors.New("./tmp must hold both the chain and the wallets of an older release")
+var ErrPrevTransactionMismatch = errors.New("previous transaction of a partial transaction does not match what it spends, create the file again")
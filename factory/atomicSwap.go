@@ -0,0 +1,97 @@
+package factory
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+// NewAtomicSwapContract locks funds to recipient until lockTime, after which
+// they can be refunded. Both addresses have to be pay to public key hash.
+func NewAtomicSwapContract(recipient, refund string, secretHash []byte, lockTime int64) ([]byte, error) {
+	if len(secretHash) != sha256.Size || lockTime <= 0 {
+		return nil, core.ErrNotAtomicSwap
+	}
+
+	recipientVersion, recipientHash, err := wallet.ParseAddress(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	refundVersion, refundHash, err := wallet.ParseAddress(refund)
+	if err != nil {
+		return nil, err
+	}
+
+	if !wallet.IsPubKeyHashVersion(recipientVersion) || !wallet.IsPubKeyHashVersion(refundVersion) {
+		return nil, core.ErrInvalidAddress
+	}
+
+	return script.AtomicSwapScript(script.AtomicSwap{
+		SecretHash:    secretHash,
+		RecipientHash: recipientHash,
+		RefundHash:    refundHash,
+		LockTime:      lockTime,
+	}), nil
+}
+
+// SpendAtomicSwap builds and signs the transaction paying the contract output
+// at outpoint to address. With a secret it redeems the contract, without it
+// it is a refund, which carries the contract lock time so that it can't be
+// mined before.
+func (chain *Blockchain) SpendAtomicSwap(outpoint Outpoint, contract []byte, secret []byte, to string, fee int, w wallet.Wallet) (*Transaction, error) {
+	swap, ok := script.ExtractAtomicSwap(contract)
+	if !ok {
+		return nil, core.ErrNotAtomicSwap
+	}
+
+	utxos := UTXOSet{chain}
+
+	unspent, ok := utxos.FindResult(outpoint.TxID, outpoint.Index)
+	if !ok {
+		return nil, core.ErrMissingInput
+	}
+
+	if !unspent.Result.IsLockedWith(script.PayToScriptHash(script.Hash160(contract))) {
+		return nil, core.ErrContractMismatch
+	}
+
+	if fee < 0 || fee >= unspent.Result.Value {
+		return nil, core.ErrInvalidFee
+	}
+
+	party := swap.RefundHash
+	lockTime := swap.LockTime
+
+	if secret != nil {
+		hash := sha256.Sum256(secret)
+		if !bytes.Equal(hash[:], swap.SecretHash) {
+			return nil, core.ErrInvalidSecret
+		}
+
+		party = swap.RecipientHash
+		lockTime = 0
+	}
+
+	if !bytes.Equal(wallet.PublicKeyHash(w.PublicKey), party) {
+		return nil, core.ErrNotContractParty
+	}
+
+	tx := NewRawTransaction([]Outpoint{outpoint}, []Payment{{Address: to, Amount: unspent.Result.Value - fee}}, lockTime, 0)
+
+	signature, err := tx.RequestSignature(0, contract, SigHashAll, w.Signer())
+	if err != nil {
+		return nil, err
+	}
+
+	if secret != nil {
+		tx.Requests[0].UnlockingScript = script.UnlockAtomicSwapRedeem(signature, w.PublicKey, secret, contract)
+	} else {
+		tx.Requests[0].UnlockingScript = script.UnlockAtomicSwapRefund(signature, w.PublicKey, contract)
+	}
+
+	return tx, nil
+}
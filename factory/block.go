@@ -2,37 +2,124 @@ package factory
 
 import (
 	"bytes"
-	"encoding/gob"
+	"time"
 
 	"github.com/wilmacedo/willchain-go/core"
 	"github.com/wilmacedo/willchain-go/factory/merkle"
 )
 
 type Block struct {
+	Version      uint32
 	Hash         []byte
 	Transactions []*Transaction
 	PreviousHash []byte
 	Nonce        int
+	Height       int
+	Timestamp    int64
+}
+
+// Header is a block without its transactions, with the merkle roots its hash
+// commits to. It is all a light client keeps of a block.
+type Header struct {
+	Block       *Block
+	MerkleRoot  []byte
+	WitnessRoot []byte
+}
+
+func (block *Block) Header() *Header {
+	header := *block
+	header.Transactions = nil
+
+	return &Header{
+		Block:       &header,
+		MerkleRoot:  block.HashTransactions(),
+		WitnessRoot: block.HashWitnesses(),
+	}
+}
+
+// Validate checks the proof of work of the header.
+func (header *Header) Validate() bool {
+	pow := NewProof(header.Block)
+	pow.root = header.MerkleRoot
+	pow.witnessRoot = header.WitnessRoot
+
+	return pow.Validate()
+}
+
+// merkleLeaf is what the merkle tree of the block hashes for tx: its ID, or
+// the whole serialized transaction up to SerializedMerkleVersion.
+func (block *Block) merkleLeaf(tx *Transaction) []byte {
+	if block.Version <= SerializedMerkleVersion {
+		return tx.Serialize()
+	}
+
+	return tx.ID
+}
+
+func (block *Block) merkleLeaves() [][]byte {
+	var leaves [][]byte
+
+	for _, tx := range block.Transactions {
+		leaves = append(leaves, block.merkleLeaf(tx))
+	}
+
+	return leaves
 }
 
 func (block *Block) HashTransactions() []byte {
-	var txHashes [][]byte
+	if block.Version <= SerializedMerkleVersion {
+		return merkle.LegacyRoot(block.merkleLeaves())
+	}
+
+	return merkle.Root(block.merkleLeaves())
+}
+
+// HashWitnesses is the merkle root of the witness hashes, which the merkle
+// tree of transaction IDs leaves out. Older blocks have none.
+func (block *Block) HashWitnesses() []byte {
+	if block.Version <= SerializedMerkleVersion {
+		return nil
+	}
 
+	var hashes [][]byte
 	for _, tx := range block.Transactions {
-		txHashes = append(txHashes, tx.Serialize())
+		hashes = append(hashes, tx.WitnessHash())
 	}
 
-	tree := merkle.NewMerkleTree(txHashes)
+	return merkle.Root(hashes)
+}
+
+// TransactionProof is the merkle path from a transaction of the block to
+// HashTransactions.
+func (block *Block) TransactionProof(txID []byte) (*merkle.Proof, error) {
+	index := -1
 
-	return tree.RootNode.Data
+	for i, tx := range block.Transactions {
+		if bytes.Equal(tx.ID, txID) {
+			index = i
+		}
+	}
+
+	if block.Version <= SerializedMerkleVersion {
+		return merkle.NewLegacyProof(block.merkleLeaves(), index)
+	}
+
+	return merkle.NewProof(block.merkleLeaves(), index)
+}
+
+func (block *Block) VerifyTransactionProof(tx *Transaction, proof *merkle.Proof) bool {
+	return merkle.VerifyProof(block.HashTransactions(), block.merkleLeaf(tx), proof)
 }
 
-func CreateBlock(txs []*Transaction, previousHash []byte) *Block {
+func CreateBlock(txs []*Transaction, previousHash []byte, height int) *Block {
 	block := &Block{
+		Version:      BlockVersion,
 		Hash:         []byte{},
 		Transactions: txs,
 		PreviousHash: previousHash,
 		Nonce:        0,
+		Height:       height,
+		Timestamp:    time.Now().Unix(),
 	}
 	pow := NewProof(block)
 	nonce, hash := pow.Run()
@@ -44,24 +131,11 @@ func CreateBlock(txs []*Transaction, previousHash []byte) *Block {
 }
 
 func Genesis(coinbase *Transaction) *Block {
-	return CreateBlock([]*Transaction{coinbase}, []byte{})
-}
-
-func (block *Block) Serialize() []byte {
-	var result bytes.Buffer
-	encoder := gob.NewEncoder(&result)
-
-	err := encoder.Encode(block)
-	core.Handle(err)
-
-	return result.Bytes()
+	return CreateBlock([]*Transaction{coinbase}, []byte{}, 0)
 }
 
 func Deserialize(data []byte) *Block {
-	var block *Block
-	decoder := gob.NewDecoder(bytes.NewBuffer(data))
-
-	err := decoder.Decode(&block)
+	block, err := deserializeStoredBlock(data)
 	core.Handle(err)
 
 	return block
@@ -0,0 +1,125 @@
+package factory
+
+import (
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/gcs"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+	"github.com/wilmacedo/willchain-go/storage"
+)
+
+const (
+	filterPrefix       = "cf-"
+	filterHeaderPrefix = "cfh-"
+)
+
+func filterKey(blockHash []byte) []byte {
+	return append([]byte(filterPrefix), blockHash...)
+}
+
+func filterHeaderKey(blockHash []byte) []byte {
+	return append([]byte(filterHeaderPrefix), blockHash...)
+}
+
+// FilterKey is the key the filter of a block is hashed with.
+func FilterKey(blockHash []byte) []byte {
+	key := make([]byte, gcs.KeySize)
+	copy(key, blockHash)
+
+	return key
+}
+
+// FilterOutpoint is the filter item of a spent result: its txid followed by
+// its index as a uint32.
+func FilterOutpoint(txID []byte, index int) []byte {
+	w := wire.NewWriter()
+	w.WriteRaw(txID)
+	w.WriteUint32(uint32(int32(index)))
+
+	return w.Bytes()
+}
+
+// Filter is the compact filter of the block: the locking scripts of its
+// spendable results and the outpoints its requests spend.
+func (block *Block) Filter() *gcs.Filter {
+	var items [][]byte
+
+	for _, tx := range block.Transactions {
+		if !tx.IsCoinbase() {
+			for _, req := range tx.Requests {
+				items = append(items, FilterOutpoint(req.ID, req.Out))
+			}
+		}
+
+		for _, res := range tx.Results {
+			if len(res.LockingScript) > 0 && !script.IsUnspendable(res.LockingScript) {
+				items = append(items, res.LockingScript)
+			}
+		}
+	}
+
+	return gcs.Build(FilterKey(block.Hash), items)
+}
+
+// IndexFilters stores the filters and filter headers of the blocks that
+// don't have them yet, walking back from the tip to the last indexed one, so
+// chains created before the index catch up too.
+func (chain *Blockchain) IndexFilters() int {
+	var pending []*Block
+	var previous []byte
+
+	iter := chain.Iterator()
+	for {
+		block := iter.Next()
+
+		header, err := chain.Database.Get(filterHeaderKey(block.Hash))
+		if err == nil {
+			previous = header
+			break
+		} else if err != core.ErrKeyNotFound {
+			core.Handle(err)
+		}
+
+		pending = append(pending, block)
+
+		if len(block.PreviousHash) == 0 {
+			break
+		}
+	}
+
+	batch := chain.Database.NewBatch()
+
+	for i := len(pending) - 1; i >= 0; i-- {
+		previous = putFilter(batch, pending[i], previous)
+	}
+
+	err := chain.Database.Write(batch)
+	core.Handle(err)
+
+	return len(pending)
+}
+
+// putFilter adds the filter of the block and its header, chained to the
+// previous one, to the batch and returns the header.
+func putFilter(batch storage.Batch, block *Block, previous []byte) []byte {
+	filter := block.Filter()
+	header := filter.Header(previous)
+
+	batch.Put(filterKey(block.Hash), filter.Serialize())
+	batch.Put(filterHeaderKey(block.Hash), header)
+
+	return header
+}
+
+func (chain *Blockchain) BlockFilter(blockHash []byte) (*gcs.Filter, error) {
+	data, err := chain.Database.Get(filterKey(blockHash))
+	if err != nil {
+		return nil, err
+	}
+
+	return gcs.Deserialize(data)
+}
+
+func (chain *Blockchain) FilterHeader(blockHash []byte) ([]byte, error) {
+	return chain.Database.Get(filterHeaderKey(blockHash))
+}
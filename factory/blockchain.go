@@ -2,14 +2,14 @@ package factory
 
 import (
 	"bytes"
-	"crypto/ecdsa"
 	"encoding/hex"
 	"fmt"
 	"runtime"
 
-	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/script"
 	"github.com/wilmacedo/willchain-go/storage"
+	"github.com/wilmacedo/willchain-go/wallet"
 )
 
 const (
@@ -18,17 +18,20 @@ const (
 
 type Blockchain struct {
 	LastHash []byte
-	Database *leveldb.DB
+	Database storage.Database
+	SigCache *SigCache
+
+	// Upgrades tells what opening the chain migrated or rebuilt, for the
+	// caller to report.
+	Upgrades []string
 }
 
 type Iterator struct {
 	CurrentHash []byte
-	Database    *leveldb.DB
+	Database    storage.Database
 }
 
 func InitBlockchain(address string) *Blockchain {
-	var lastHash []byte
-
 	if storage.Exists() {
 		fmt.Printf("Blockchain already exists")
 		runtime.Goexit()
@@ -37,45 +40,79 @@ func InitBlockchain(address string) *Blockchain {
 	db, err := storage.Open()
 	core.Handle(err)
 
-	if _, err := db.Get([]byte("lh"), nil); err == leveldb.ErrNotFound {
-		coinbaseTx := CoinbaseTX(address, genesisData)
-
-		genesis := Genesis(coinbaseTx)
-		fmt.Println("Genesis created")
-
-		err := db.Put(genesis.Hash, genesis.Serialize(), nil)
-		core.Handle(err)
+	return NewBlockchain(db, address)
+}
 
-		err = db.Put([]byte("lh"), genesis.Hash, nil)
+// NewBlockchain starts a chain in db with a genesis block paying to address,
+// or picks up the chain db already has.
+func NewBlockchain(db storage.Database, address string) *Blockchain {
+	if _, err := db.Get([]byte("lh")); err != core.ErrKeyNotFound {
 		core.Handle(err)
 
-		lastHash = genesis.Hash
-	} else {
-		lastHash, err = db.Get([]byte("lh"), nil)
-		core.Handle(err)
+		return LoadBlockchain(db)
 	}
 
 	chain := &Blockchain{
-		LastHash: lastHash,
 		Database: db,
+		SigCache: NewSigCache(DefaultSigCacheSize),
 	}
 
+	coinbaseTx := CoinbaseTX(address, genesisData, 0)
+
+	genesis := Genesis(coinbaseTx)
+	chain.connectBlock(genesis)
+	fmt.Println("Genesis created")
+
 	return chain
 }
 
-func (chain *Blockchain) AddBlock(transactions []*Transaction) {
-	lastHash, err := chain.Database.Get([]byte("lh"), nil)
+func (chain *Blockchain) AddBlock(transactions []*Transaction) *Block {
+	lastHash, err := chain.Database.Get([]byte("lh"))
 	core.Handle(err)
 
-	newBlock := CreateBlock(transactions, lastHash)
-
-	err = chain.Database.Put(newBlock.Hash, newBlock.Serialize(), nil)
+	encodedBlock, err := chain.Database.Get(lastHash)
 	core.Handle(err)
 
-	err = chain.Database.Put([]byte("lh"), newBlock.Hash, nil)
+	lastBlock := Deserialize(encodedBlock)
+
+	newBlock := CreateBlock(transactions, lastHash, lastBlock.Height+1)
+	core.Handle(chain.checkBlock(newBlock))
+
+	chain.connectBlock(newBlock)
+
+	return newBlock
+}
+
+// connectBlock stores the block as the new tip with the updates of the
+// unspent outputs and of the filter index in a single batch, so a crash
+// leaves the database either before or after the block.
+func (chain *Blockchain) connectBlock(block *Block) {
+	var previous []byte
+
+	batch := chain.Database.NewBatch()
+
+	// The filter index is up to the tip since the chain was opened.
+	if len(block.PreviousHash) > 0 {
+		header, err := chain.FilterHeader(block.PreviousHash)
+		core.Handle(err)
+
+		previous = header
+	} else {
+		putSchemaVersion(batch, SchemaVersion)
+	}
+
+	batch.Put(block.Hash, block.Serialize())
+	batch.Put([]byte("lh"), block.Hash)
+
+	utxos := UTXOSet{chain}
+	utxos.connect(batch, block)
+
+	putFilter(batch, block, previous)
+
+	err := chain.Database.Write(batch)
 	core.Handle(err)
 
-	chain.LastHash = newBlock.Hash
+	chain.LastHash = block.Hash
 }
 
 func ContinueBlockchain(address string) *Blockchain {
@@ -84,19 +121,26 @@ func ContinueBlockchain(address string) *Blockchain {
 		runtime.Goexit()
 	}
 
-	var lastHash []byte
-
 	db, err := storage.Open()
 	core.Handle(err)
 
-	lastHash, err = db.Get([]byte("lh"), nil)
+	return LoadBlockchain(db)
+}
+
+// LoadBlockchain continues the chain of db, which must have one.
+func LoadBlockchain(db storage.Database) *Blockchain {
+	lastHash, err := db.Get([]byte("lh"))
 	core.Handle(err)
 
 	chain := &Blockchain{
 		LastHash: lastHash,
 		Database: db,
+		SigCache: NewSigCache(DefaultSigCacheSize),
 	}
 
+	core.Handle(chain.Migrate())
+	core.Handle(chain.CheckConsistency())
+
 	return chain
 }
 
@@ -112,7 +156,7 @@ func (chain *Blockchain) Iterator() *Iterator {
 func (iter *Iterator) Next() *Block {
 	var block *Block
 
-	encodedBlock, err := iter.Database.Get(iter.CurrentHash, nil)
+	encodedBlock, err := iter.Database.Get(iter.CurrentHash)
 	core.Handle(err)
 
 	block = Deserialize(encodedBlock)
@@ -122,9 +166,47 @@ func (iter *Iterator) Next() *Block {
 	return block
 }
 
-func (chain *Blockchain) FindUnspentTransactions(pubKeyHash []byte) []Transaction {
-	var unspentTxs []Transaction
+// Block returns the block stored under hash.
+func (chain *Blockchain) Block(hash []byte) (*Block, error) {
+	data, err := chain.Database.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := deserializeStoredBlock(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(block.Hash, hash) {
+		return nil, core.ErrInvalidBlock
+	}
+
+	return block, nil
+}
+
+// Headers returns up to count headers of the chain, from the height from up.
+func (chain *Blockchain) Headers(from, count int) []*Header {
+	var headers []*Header
 
+	iter := chain.Iterator()
+	for {
+		block := iter.Next()
+
+		if block.Height >= from && block.Height < from+count {
+			headers = append([]*Header{block.Header()}, headers...)
+		}
+
+		if block.Height <= from || len(block.PreviousHash) == 0 {
+			break
+		}
+	}
+
+	return headers
+}
+
+func (chain *Blockchain) FindUTXO() map[string]TXResults {
+	utxo := make(map[string]TXResults)
 	spentTXRes := make(map[string][]int)
 
 	iter := chain.Iterator()
@@ -136,27 +218,30 @@ func (chain *Blockchain) FindUnspentTransactions(pubKeyHash []byte) []Transactio
 			txHash := hex.EncodeToString(tx.ID)
 
 		Result:
-			for resHashx, res := range tx.Results {
-				if spentTXRes[txHash] != nil {
-					for _, spentRes := range spentTXRes[txHash] {
-						if spentRes == resHashx {
-							continue Result
-						}
-					}
+			for resIdx, res := range tx.Results {
+				if script.IsUnspendable(res.LockingScript) {
+					continue
 				}
 
-				if res.IsLockWithKey(pubKeyHash) {
-					unspentTxs = append(unspentTxs, *tx)
+				for _, spentRes := range spentTXRes[txHash] {
+					if spentRes == resIdx {
+						continue Result
+					}
 				}
+
+				results := utxo[txHash]
+				results.Height = block.Height
+				results.Time = block.Timestamp
+				results.Indexes = append(results.Indexes, resIdx)
+				results.Results = append(results.Results, res)
+				utxo[txHash] = results
 			}
 
 			if !tx.IsCoinbase() {
 				for _, req := range tx.Requests {
-					if req.UsesKey(pubKeyHash) {
-						reqHash := hex.EncodeToString(req.ID)
+					reqHash := hex.EncodeToString(req.ID)
 
-						spentTXRes[reqHash] = append(spentTXRes[reqHash], req.Out)
-					}
+					spentTXRes[reqHash] = append(spentTXRes[reqHash], req.Out)
 				}
 			}
 		}
@@ -166,57 +251,87 @@ func (chain *Blockchain) FindUnspentTransactions(pubKeyHash []byte) []Transactio
 		}
 	}
 
-	return unspentTxs
+	return utxo
+}
+
+func (chain *Blockchain) Height() int {
+	encodedBlock, err := chain.Database.Get(chain.LastHash)
+	core.Handle(err)
+
+	return Deserialize(encodedBlock).Height
 }
 
-func (chain *Blockchain) FindResTX(pubKeyHash []byte) []TXResult {
-	var resTxs []TXResult
-	unspentTxs := chain.FindUnspentTransactions(pubKeyHash)
+func (chain *Blockchain) FindTransaction(ID []byte) (*Transaction, error) {
+	_, tx, err := chain.FindTransactionBlock(ID)
+
+	return tx, err
+}
+
+// FindTransactionBlock returns a transaction and the block it is in.
+func (chain *Blockchain) FindTransactionBlock(ID []byte) (*Block, *Transaction, error) {
+	iter := chain.Iterator()
+
+	for {
+		block := iter.Next()
 
-	for _, tx := range unspentTxs {
-		for _, res := range tx.Results {
-			if res.IsLockWithKey(pubKeyHash) {
-				resTxs = append(resTxs, res)
+		for _, tx := range block.Transactions {
+			if bytes.Equal(tx.ID, ID) {
+				return block, tx, nil
 			}
 		}
+
+		if len(block.PreviousHash) == 0 {
+			break
+		}
 	}
 
-	return resTxs
+	return nil, nil, core.ErrNilTransaction
 }
 
-func (chain *Blockchain) FindSpendableResults(pubKeyHash []byte, amount int) (int, map[string][]int) {
-	unspentRes := make(map[string][]int)
-	unspentTxs := chain.FindUnspentTransactions(pubKeyHash)
-	accumlated := 0
+// FindData returns the first transaction with a data output carrying data
+// and the block it is in.
+func (chain *Blockchain) FindData(data []byte) (*Block, *Transaction, bool) {
+	var found *Transaction
+	var foundBlock *Block
 
-Work:
-	for _, tx := range unspentTxs {
-		txHash := hex.EncodeToString(tx.ID)
+	iter := chain.Iterator()
 
-		for resHashx, res := range tx.Results {
-			if res.IsLockWithKey(pubKeyHash) && accumlated < amount {
-				accumlated += res.Value
-				unspentRes[txHash] = append(unspentRes[txHash], resHashx)
+	for {
+		block := iter.Next()
 
-				if accumlated >= amount {
-					break Work
+		for _, tx := range block.Transactions {
+			for _, res := range tx.Results {
+				if carried, ok := script.ExtractNullData(res.LockingScript); ok && bytes.Equal(carried, data) {
+					found, foundBlock = tx, block
 				}
 			}
 		}
+
+		if len(block.PreviousHash) == 0 {
+			break
+		}
 	}
 
-	return accumlated, unspentRes
+	return foundBlock, found, found != nil
 }
 
-func (chain *Blockchain) FindTransaction(ID []byte) (*Transaction, error) {
+// FindSpender returns the transaction spending an output and the index of
+// the request that spends it.
+func (chain *Blockchain) FindSpender(outpoint Outpoint) (*Transaction, int, bool) {
 	iter := chain.Iterator()
 
 	for {
 		block := iter.Next()
 
 		for _, tx := range block.Transactions {
-			if bytes.Equal(tx.ID, ID) {
-				return tx, nil
+			if tx.IsCoinbase() {
+				continue
+			}
+
+			for reqId, req := range tx.Requests {
+				if bytes.Equal(req.ID, outpoint.TxID) && req.Out == outpoint.Index {
+					return tx, reqId, true
+				}
 			}
 		}
 
@@ -225,10 +340,10 @@ func (chain *Blockchain) FindTransaction(ID []byte) (*Transaction, error) {
 		}
 	}
 
-	return nil, core.ErrNilTransaction
+	return nil, 0, false
 }
 
-func (chain *Blockchain) SignTransaction(tx *Transaction, privateKey ecdsa.PrivateKey) {
+func (chain *Blockchain) SignTransaction(tx *Transaction, w wallet.Wallet) {
 	prevTXs := make(map[string]Transaction)
 
 	for _, req := range tx.Requests {
@@ -238,7 +353,43 @@ func (chain *Blockchain) SignTransaction(tx *Transaction, privateKey ecdsa.Priva
 		prevTXs[hex.EncodeToString(prevTX.ID)] = *prevTX
 	}
 
-	tx.Sign(privateKey, prevTXs)
+	tx.Sign(w, prevTXs)
+}
+
+// SignWithWallets signs every request whose previous result is locked to a key
+// found in wallets and reports whether the transaction is completely signed.
+func (chain *Blockchain) SignWithWallets(tx *Transaction, wallets *wallet.Wallets, hashType SigHashType) (bool, error) {
+	prevTXs := make(map[string]Transaction)
+	signers := make(map[int]wallet.Wallet)
+
+	for reqId, req := range tx.Requests {
+		prevTX, err := chain.FindTransaction(req.ID)
+		if err != nil || req.Out < 0 || req.Out >= len(prevTX.Results) {
+			continue
+		}
+
+		prevTXs[hex.EncodeToString(prevTX.ID)] = *prevTX
+
+		pubKeyHash, ok := script.ExtractPubKeyHash(prevTX.Results[req.Out].LockingScript)
+		if !ok {
+			continue
+		}
+
+		w, ok := wallets.FindByPubKeyHash(pubKeyHash)
+		if !ok {
+			continue
+		}
+
+		signers[reqId] = w
+	}
+
+	for reqId, w := range signers {
+		if err := tx.SignRequest(reqId, w, prevTXs, hashType); err != nil {
+			return false, err
+		}
+	}
+
+	return len(signers) == len(tx.Requests), nil
 }
 
 func (chain *Blockchain) VerifyTransaction(tx *Transaction) bool {
@@ -0,0 +1,128 @@
+// Package bloom implements the BIP37 bloom filters light clients load on a
+// connection, so the node only sends them the transactions they may care
+// about.
+package bloom
+
+import (
+	"math"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+// Filters are capped so a peer can't make the node do too much work for
+// each transaction.
+const (
+	MaxFilterSize  = 36000
+	MaxHashFuncs   = 50
+	MaxElementSize = 520
+
+	seedStep = 0xfba4c795
+)
+
+// UpdateFlag tells the node what to add to the filter when a transaction
+// matches.
+type UpdateFlag byte
+
+const (
+	UpdateNone UpdateFlag = 0
+	// UpdateAll adds the outpoints of the matched results, so the
+	// transactions spending them match too.
+	UpdateAll UpdateFlag = 1
+)
+
+type Filter struct {
+	Data      []byte
+	HashFuncs uint32
+	Tweak     uint32
+	Flags     UpdateFlag
+}
+
+// NewFilter sizes a filter for the number of elements and false positive
+// rate, within the protocol limits. Sizes are rounded down to whole bits then
+// bytes before the hash functions are counted, as BIP37 does.
+func NewFilter(elements int, falsePositiveRate float64, tweak uint32, flags UpdateFlag) *Filter {
+	if elements < 1 {
+		elements = 1
+	}
+
+	bits := -1 / (math.Ln2 * math.Ln2) * float64(elements) * math.Log(falsePositiveRate)
+	size := int(math.Min(bits, MaxFilterSize*8)) / 8
+	if size < 1 {
+		size = 1
+	}
+
+	hashFuncs := float64(size*8) / float64(elements) * math.Ln2
+	hashFuncs = math.Max(1, math.Min(hashFuncs, MaxHashFuncs))
+
+	return &Filter{
+		Data:      make([]byte, size),
+		HashFuncs: uint32(hashFuncs),
+		Tweak:     tweak,
+		Flags:     flags,
+	}
+}
+
+func (filter *Filter) bit(hashNum uint32, data []byte) uint32 {
+	return murmur3(hashNum*seedStep+filter.Tweak, data) % (uint32(len(filter.Data)) * 8)
+}
+
+func (filter *Filter) Add(data []byte) {
+	if len(filter.Data) == 0 {
+		return
+	}
+
+	for i := uint32(0); i < filter.HashFuncs; i++ {
+		bit := filter.bit(i, data)
+		filter.Data[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+func (filter *Filter) Matches(data []byte) bool {
+	if len(filter.Data) == 0 {
+		return false
+	}
+
+	for i := uint32(0); i < filter.HashFuncs; i++ {
+		bit := filter.bit(i, data)
+		if filter.Data[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Filter layout: data bytes, hash functions uint32, tweak uint32, flags
+// uint8.
+func (filter *Filter) Serialize() []byte {
+	w := wire.NewWriter()
+
+	w.WriteBytes(filter.Data)
+	w.WriteUint32(filter.HashFuncs)
+	w.WriteUint32(filter.Tweak)
+	w.WriteUint8(uint8(filter.Flags))
+
+	return w.Bytes()
+}
+
+func Deserialize(data []byte) (*Filter, error) {
+	r := wire.NewReader(data)
+
+	filter := &Filter{
+		Data:      r.ReadBytes(),
+		HashFuncs: r.ReadUint32(),
+		Tweak:     r.ReadUint32(),
+		Flags:     UpdateFlag(r.ReadUint8()),
+	}
+
+	if err := r.Finish(); err != nil {
+		return nil, err
+	}
+
+	if len(filter.Data) > MaxFilterSize || filter.HashFuncs > MaxHashFuncs || filter.Flags > UpdateAll {
+		return nil, core.ErrInvalidBloomFilter
+	}
+
+	return filter, nil
+}
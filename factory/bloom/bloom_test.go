@@ -0,0 +1,128 @@
+package bloom
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+func decodeHex(t *testing.T, data string) []byte {
+	decoded, err := hex.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return decoded
+}
+
+// Vectors of the Bitcoin Core MurmurHash3 tests.
+func TestMurmur3(t *testing.T) {
+	tests := []struct {
+		hash uint32
+		seed uint32
+		data string
+	}{
+		{0x00000000, 0x00000000, ""},
+		{0x6a396f08, 0xfba4c795, ""},
+		{0x81f16f39, 0xffffffff, ""},
+		{0x514e28b7, 0x00000000, "00"},
+		{0xea3f0b17, 0xfba4c795, "00"},
+		{0xfd6cf10d, 0x00000000, "ff"},
+		{0x16c6b7ab, 0x00000000, "0011"},
+		{0x8eb51c3d, 0x00000000, "001122"},
+		{0xb4471bf8, 0x00000000, "00112233"},
+		{0xe2301fa8, 0x00000000, "0011223344"},
+		{0xfc2e4a15, 0x00000000, "001122334455"},
+		{0xb074502c, 0x00000000, "00112233445566"},
+		{0x8034d2a0, 0x00000000, "0011223344556677"},
+		{0xb4698def, 0x00000000, "001122334455667788"},
+	}
+
+	for _, test := range tests {
+		if hash := murmur3(test.seed, decodeHex(t, test.data)); hash != test.hash {
+			t.Errorf("seed %#x data %q: %#08x, want %#08x", test.seed, test.data, hash, test.hash)
+		}
+	}
+}
+
+// Vectors of the BIP37 filter tests of Bitcoin Core.
+func TestFilter(t *testing.T) {
+	elements := []string{
+		"99108ad8ed9bb6274d3980bab5a85c048f0950c8",
+		"b5a2c786d9ef4658287ced5914b37a1b4aa32eee",
+		"b9300670b4c5366e95b2699e8b18bc75e5f729c5",
+	}
+
+	tests := []struct {
+		tweak      uint32
+		serialized string
+	}{
+		{0, "03614e9b050000000000000001"},
+		{2147483649, "03ce4299050000000100008001"},
+	}
+
+	for _, test := range tests {
+		filter := NewFilter(len(elements), 0.01, test.tweak, UpdateAll)
+
+		for _, element := range elements {
+			filter.Add(decodeHex(t, element))
+		}
+
+		for _, element := range elements {
+			if !filter.Matches(decodeHex(t, element)) {
+				t.Errorf("tweak %d: %s does not match", test.tweak, element)
+			}
+		}
+
+		if filter.Matches(decodeHex(t, "19108ad8ed9bb6274d3980bab5a85c048f0950c8")) {
+			t.Errorf("tweak %d: element not added matches", test.tweak)
+		}
+
+		if serialized := hex.EncodeToString(filter.Serialize()); serialized != test.serialized {
+			t.Errorf("tweak %d: serialized %s, want %s", test.tweak, serialized, test.serialized)
+		}
+
+		decoded, err := Deserialize(filter.Serialize())
+		if err != nil || hex.EncodeToString(decoded.Serialize()) != test.serialized {
+			t.Errorf("tweak %d: round trip %v", test.tweak, err)
+		}
+	}
+}
+
+func TestEmptyFilter(t *testing.T) {
+	filter := &Filter{HashFuncs: 3}
+	filter.Add([]byte("element"))
+
+	if filter.Matches([]byte("element")) {
+		t.Error("filter without data matches")
+	}
+}
+
+func TestDeserializeLimits(t *testing.T) {
+	encode := func(size int, hashFuncs uint32, flags UpdateFlag) []byte {
+		filter := &Filter{Data: make([]byte, size), HashFuncs: hashFuncs, Flags: flags}
+
+		return filter.Serialize()
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		ok   bool
+	}{
+		{"largest", encode(MaxFilterSize, MaxHashFuncs, UpdateAll), true},
+		{"too large", encode(MaxFilterSize+1, 1, UpdateNone), false},
+		{"too many hash functions", encode(10, MaxHashFuncs+1, UpdateNone), false},
+		{"unknown flags", encode(10, 1, UpdateAll+1), false},
+		{"truncated", encode(10, 1, UpdateNone)[:14], false},
+		{"trailing bytes", append(encode(10, 1, UpdateNone), 0), false},
+		{"empty", wire.NewWriter().Bytes(), false},
+	}
+
+	for _, test := range tests {
+		if _, err := Deserialize(test.data); (err == nil) != test.ok {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package factory
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+const (
+	StrategyLargestFirst  = "largest"
+	StrategySmallestFirst = "smallest"
+	StrategyBranchBound   = "bnb"
+	StrategyRandom        = "random"
+
+	branchBoundMaxTries = 100000
+)
+
+type UnspentResult struct {
+	TxID          []byte
+	Index         int
+	Result        TXResult
+	Confirmations int
+	Height        int
+	Time          int64
+}
+
+type Outpoint struct {
+	TxID  []byte
+	Index int
+}
+
+type CoinSelector interface {
+	Select(candidates []UnspentResult, target int) ([]UnspentResult, error)
+}
+
+type LargestFirst struct{}
+
+type SmallestFirst struct{}
+
+// BranchBound looks for a set of coins that sums exactly to the target, so no
+// change output is needed. When no exact match exists it falls back to
+// LargestFirst.
+type BranchBound struct{}
+
+type RandomSelection struct{}
+
+// ManualSelection spends exactly the given outpoints. Each one must be among
+// the candidates, which means it is unspent and owned by the wallet.
+type ManualSelection struct {
+	Outpoints []Outpoint
+}
+
+func ParseOutpoint(value string) (Outpoint, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return Outpoint{}, core.ErrInvalidOutpoint
+	}
+
+	txID, err := hex.DecodeString(parts[0])
+	if err != nil || len(txID) == 0 {
+		return Outpoint{}, core.ErrInvalidOutpoint
+	}
+
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 {
+		return Outpoint{}, core.ErrInvalidOutpoint
+	}
+
+	return Outpoint{TxID: txID, Index: index}, nil
+}
+
+func NewCoinSelector(strategy string) (CoinSelector, error) {
+	switch strategy {
+	case "", StrategyLargestFirst:
+		return LargestFirst{}, nil
+	case StrategySmallestFirst:
+		return SmallestFirst{}, nil
+	case StrategyBranchBound:
+		return BranchBound{}, nil
+	case StrategyRandom:
+		return RandomSelection{}, nil
+	}
+
+	return nil, core.ErrUnknownStrategy
+}
+
+func (LargestFirst) Select(candidates []UnspentResult, target int) ([]UnspentResult, error) {
+	coins := sortedByValue(candidates)
+
+	for i, j := 0, len(coins)-1; i < j; i, j = i+1, j-1 {
+		coins[i], coins[j] = coins[j], coins[i]
+	}
+
+	return accumulate(coins, target)
+}
+
+func (SmallestFirst) Select(candidates []UnspentResult, target int) ([]UnspentResult, error) {
+	return accumulate(sortedByValue(candidates), target)
+}
+
+func (BranchBound) Select(candidates []UnspentResult, target int) ([]UnspentResult, error) {
+	coins := sortedByValue(candidates)
+
+	for i, j := 0, len(coins)-1; i < j; i, j = i+1, j-1 {
+		coins[i], coins[j] = coins[j], coins[i]
+	}
+
+	remaining := make([]int, len(coins)+1)
+	for i := len(coins) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + coins[i].Result.Value
+	}
+
+	var selected []int
+	tries := 0
+
+	var search func(depth, acc int) bool
+	search = func(depth, acc int) bool {
+		tries++
+
+		if acc == target {
+			return true
+		}
+
+		if acc > target || depth == len(coins) || acc+remaining[depth] < target || tries > branchBoundMaxTries {
+			return false
+		}
+
+		selected = append(selected, depth)
+		if search(depth+1, acc+coins[depth].Result.Value) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+
+		return search(depth+1, acc)
+	}
+
+	if !search(0, 0) {
+		return LargestFirst{}.Select(candidates, target)
+	}
+
+	var result []UnspentResult
+	for _, i := range selected {
+		result = append(result, coins[i])
+	}
+
+	return result, nil
+}
+
+func (RandomSelection) Select(candidates []UnspentResult, target int) ([]UnspentResult, error) {
+	coins := make([]UnspentResult, len(candidates))
+	copy(coins, candidates)
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	random.Shuffle(len(coins), func(i, j int) {
+		coins[i], coins[j] = coins[j], coins[i]
+	})
+
+	return accumulate(coins, target)
+}
+
+func (m ManualSelection) Select(candidates []UnspentResult, target int) ([]UnspentResult, error) {
+	var selected []UnspentResult
+
+	seen := make(map[string]bool)
+
+Outpoints:
+	for _, outpoint := range m.Outpoints {
+		key := fmt.Sprintf("%x:%d", outpoint.TxID, outpoint.Index)
+		if seen[key] {
+			return nil, core.ErrDuplicateInput
+		}
+		seen[key] = true
+
+		for _, candidate := range candidates {
+			if bytes.Equal(candidate.TxID, outpoint.TxID) && candidate.Index == outpoint.Index {
+				selected = append(selected, candidate)
+				continue Outpoints
+			}
+		}
+
+		return nil, fmt.Errorf("%w: %s", core.ErrUnknownInput, key)
+	}
+
+	if SumResults(selected) < target {
+		return nil, core.ErrEnoughFunds
+	}
+
+	return selected, nil
+}
+
+func SumResults(results []UnspentResult) int {
+	total := 0
+
+	for _, res := range results {
+		total += res.Result.Value
+	}
+
+	return total
+}
+
+func sortedByValue(candidates []UnspentResult) []UnspentResult {
+	coins := make([]UnspentResult, len(candidates))
+	copy(coins, candidates)
+
+	sort.SliceStable(coins, func(i, j int) bool {
+		return coins[i].Result.Value < coins[j].Result.Value
+	})
+
+	return coins
+}
+
+func accumulate(coins []UnspentResult, target int) ([]UnspentResult, error) {
+	var selected []UnspentResult
+	acc := 0
+
+	for _, coin := range coins {
+		if acc >= target {
+			break
+		}
+
+		selected = append(selected, coin)
+		acc += coin.Result.Value
+	}
+
+	if acc < target {
+		return nil, core.ErrEnoughFunds
+	}
+
+	return selected, nil
+}
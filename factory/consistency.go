@@ -0,0 +1,40 @@
+package factory
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// CheckConsistency compares the tip with the tips of the unspent outputs and
+// filter indexes. Indexes behind the tip, left by older releases that didn't
+// write a block in one batch or didn't keep them, are rebuilt. A tip that
+// can't be read can't be repaired.
+func (chain *Blockchain) CheckConsistency() error {
+	data, err := chain.Database.Get(chain.LastHash)
+	if err != nil {
+		return fmt.Errorf("%w: tip block %x: %v", core.ErrCorruptDatabase, chain.LastHash, err)
+	}
+
+	block, err := deserializeStoredBlock(data)
+	if err != nil || !bytes.Equal(block.Hash, chain.LastHash) {
+		return fmt.Errorf("%w: tip block %x can't be decoded", core.ErrCorruptDatabase, chain.LastHash)
+	}
+
+	utxos := UTXOSet{chain}
+	if reindexed, err := utxos.CatchUp(); err != nil {
+		return err
+	} else if reindexed {
+		chain.Upgrades = append(chain.Upgrades, "Unspent outputs were not up to the tip, reindexed them")
+	}
+
+	if _, err := chain.FilterHeader(chain.LastHash); err == core.ErrKeyNotFound {
+		indexed := chain.IndexFilters()
+		chain.Upgrades = append(chain.Upgrades, fmt.Sprintf("Filters were not up to the tip, indexed %d blocks", indexed))
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
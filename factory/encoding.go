@@ -0,0 +1,347 @@
+package factory
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+const (
+	// LegacyVersion marks blocks and transactions migrated from the old gob
+	// storage. Their hashes can't be derived from the wire format, so it
+	// carries them.
+	LegacyVersion = 0
+
+	// InlineScriptVersion transactions keep unlocking scripts inside their
+	// requests, WitnessVersion ones in a witness section left out of the ID.
+	InlineScriptVersion = 1
+	WitnessVersion      = 2
+
+	TransactionVersion = WitnessVersion
+
+	// Blocks up to SerializedMerkleVersion hash whole serialized
+	// transactions in a legacy merkle tree. Later ones hash transaction IDs
+	// in a tagged tree and commit to the witness hashes in a second one.
+	SerializedMerkleVersion = 1
+	BlockVersion            = 2
+)
+
+// Transaction layout:
+//
+//	version uint32
+//	[legacy only] id bytes
+//	requests varint, each: txid bytes, out uint32, [inline only] unlocking script bytes, sequence uint32
+//	results varint, each: value int64, locking script bytes
+//	lock time int64
+//	[witness only] one unlocking script bytes per request
+func (tx *Transaction) encode(w *wire.Writer, witness bool) {
+	w.WriteUint32(tx.Version)
+
+	if tx.Version == LegacyVersion {
+		w.WriteBytes(tx.ID)
+	}
+
+	w.WriteVarInt(uint64(len(tx.Requests)))
+	for _, req := range tx.Requests {
+		w.WriteBytes(req.ID)
+		w.WriteUint32(uint32(int32(req.Out)))
+		if tx.Version < WitnessVersion {
+			w.WriteBytes(req.UnlockingScript)
+		}
+		w.WriteUint32(req.Sequence)
+	}
+
+	w.WriteVarInt(uint64(len(tx.Results)))
+	for _, res := range tx.Results {
+		w.WriteInt64(int64(res.Value))
+		w.WriteBytes(res.LockingScript)
+	}
+
+	w.WriteInt64(tx.LockTime)
+
+	if tx.Version >= WitnessVersion && witness {
+		for _, req := range tx.Requests {
+			w.WriteBytes(req.UnlockingScript)
+		}
+	}
+}
+
+// decodeTransaction reads legacy transactions, whose ID is carried, only when
+// stored is set.
+func decodeTransaction(r *wire.Reader, stored bool) (*Transaction, error) {
+	tx := &Transaction{Version: r.ReadUint32()}
+
+	if tx.Version > TransactionVersion || tx.Version == LegacyVersion && !stored {
+		return nil, core.ErrUnknownVersion
+	}
+
+	if tx.Version == LegacyVersion {
+		tx.ID = r.ReadBytes()
+	}
+
+	requests := r.ReadCount()
+	for i := 0; i < requests && r.Err() == nil; i++ {
+		req := TXRequest{
+			ID:  r.ReadBytes(),
+			Out: int(int32(r.ReadUint32())),
+		}
+
+		if tx.Version < WitnessVersion {
+			req.UnlockingScript = r.ReadBytes()
+		}
+		req.Sequence = r.ReadUint32()
+
+		tx.Requests = append(tx.Requests, req)
+	}
+
+	results := r.ReadCount()
+	for i := 0; i < results && r.Err() == nil; i++ {
+		tx.Results = append(tx.Results, TXResult{
+			Value:         int(r.ReadInt64()),
+			LockingScript: r.ReadBytes(),
+		})
+	}
+
+	tx.LockTime = r.ReadInt64()
+
+	if tx.Version >= WitnessVersion {
+		for i := range tx.Requests {
+			tx.Requests[i].UnlockingScript = r.ReadBytes()
+		}
+	}
+
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+
+	if tx.Version != LegacyVersion {
+		tx.ID = tx.CalculateHash()
+	}
+
+	return tx, nil
+}
+
+// Serialize is the wire encoding of the whole transaction, witness
+// included. The ID is not part of it.
+func (tx *Transaction) Serialize() []byte {
+	w := wire.NewWriter()
+	tx.encode(w, true)
+
+	return w.Bytes()
+}
+
+func DeserializeTransaction(data []byte) (*Transaction, error) {
+	r := wire.NewReader(data)
+
+	tx, err := decodeTransaction(r, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, r.Finish()
+}
+
+// header is what the proof of work hashes:
+//
+//	version uint32, previous hash bytes, merkle root bytes,
+//	[version 2 on] witness root bytes, timestamp int64, height uint32,
+//	difficulty uint32, nonce uint64
+func (block *Block) header(root, witnessRoot []byte, nonce int) []byte {
+	w := wire.NewWriter()
+
+	w.WriteUint32(block.Version)
+	w.WriteBytes(block.PreviousHash)
+	w.WriteBytes(root)
+	if block.Version > SerializedMerkleVersion {
+		w.WriteBytes(witnessRoot)
+	}
+	w.WriteInt64(block.Timestamp)
+	w.WriteUint32(uint32(block.Height))
+	w.WriteUint32(Difficulty)
+	w.WriteUint64(uint64(nonce))
+
+	return w.Bytes()
+}
+
+// encode writes the header followed by the hash for legacy blocks.
+func (header *Header) encode(w *wire.Writer) {
+	block := header.Block
+	w.WriteRaw(block.header(header.MerkleRoot, header.WitnessRoot, block.Nonce))
+
+	if block.Version == LegacyVersion {
+		w.WriteBytes(block.Hash)
+	}
+}
+
+// decodeHeader reads legacy headers only when stored is set: their hash is
+// carried instead of derived, so only the local database is trusted with
+// them.
+func decodeHeader(r *wire.Reader, stored bool) (*Header, error) {
+	var witnessRoot []byte
+
+	block := &Block{Version: r.ReadUint32()}
+	if block.Version > BlockVersion {
+		return nil, core.ErrUnknownVersion
+	} else if block.Version == LegacyVersion && !stored {
+		return nil, core.ErrLegacyHeader
+	}
+
+	block.PreviousHash = r.ReadBytes()
+	root := r.ReadBytes()
+	if block.Version > SerializedMerkleVersion {
+		witnessRoot = r.ReadBytes()
+	}
+	block.Timestamp = r.ReadInt64()
+	block.Height = int(r.ReadUint32())
+	difficulty := r.ReadUint32()
+	block.Nonce = int(r.ReadUint64())
+
+	if block.Version == LegacyVersion {
+		block.Hash = r.ReadBytes()
+	}
+
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+
+	if difficulty != Difficulty {
+		return nil, core.ErrInvalidBlock
+	}
+
+	if block.Version != LegacyVersion {
+		hash := sha256.Sum256(block.header(root, witnessRoot, block.Nonce))
+		block.Hash = hash[:]
+	}
+
+	return &Header{Block: block, MerkleRoot: root, WitnessRoot: witnessRoot}, nil
+}
+
+func (header *Header) Serialize() []byte {
+	w := wire.NewWriter()
+	header.encode(w)
+
+	return w.Bytes()
+}
+
+func DeserializeHeader(data []byte) (*Header, error) {
+	r := wire.NewReader(data)
+
+	header, err := decodeHeader(r, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return header, r.Finish()
+}
+
+// Serialize writes the header, the hash for legacy blocks, then the
+// transactions.
+func (block *Block) Serialize() []byte {
+	w := wire.NewWriter()
+	block.Header().encode(w)
+
+	w.WriteVarInt(uint64(len(block.Transactions)))
+	for _, tx := range block.Transactions {
+		tx.encode(w, true)
+	}
+
+	return w.Bytes()
+}
+
+func DeserializeBlock(data []byte) (*Block, error) {
+	return deserializeBlock(data, false)
+}
+
+// deserializeStoredBlock reads a block of the local database, where migrated
+// legacy blocks are kept.
+func deserializeStoredBlock(data []byte) (*Block, error) {
+	return deserializeBlock(data, true)
+}
+
+func deserializeBlock(data []byte, stored bool) (*Block, error) {
+	if isLegacyEncoding(data) {
+		return nil, core.ErrLegacyFormat
+	}
+
+	r := wire.NewReader(data)
+
+	header, err := decodeHeader(r, stored)
+	if err != nil {
+		return nil, err
+	}
+
+	block := header.Block
+
+	count := r.ReadCount()
+	for i := 0; i < count && r.Err() == nil; i++ {
+		tx, err := decodeTransaction(r, stored)
+		if err != nil {
+			return nil, err
+		}
+
+		block.Transactions = append(block.Transactions, tx)
+	}
+
+	if err := r.Finish(); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(header.MerkleRoot, block.HashTransactions()) || !bytes.Equal(header.WitnessRoot, block.HashWitnesses()) {
+		return nil, core.ErrInvalidBlock
+	}
+
+	return block, nil
+}
+
+// isLegacyEncoding tells gob streams apart: the wire format starts with a
+// small little endian version, a gob stream with the length of a type
+// definition.
+func isLegacyEncoding(data []byte) bool {
+	if len(data) < 4 {
+		return true
+	}
+
+	return data[1] != 0 || data[2] != 0 || data[3] != 0 || data[0] > BlockVersion
+}
+
+// TXResults layout: height uint32, time int64, then a varint count of
+// index varint, value int64, locking script bytes.
+func (ress TXResults) Serialize() []byte {
+	w := wire.NewWriter()
+
+	w.WriteUint32(uint32(ress.Height))
+	w.WriteInt64(ress.Time)
+
+	w.WriteVarInt(uint64(len(ress.Results)))
+	for i, res := range ress.Results {
+		w.WriteVarInt(uint64(ress.Indexes[i]))
+		w.WriteInt64(int64(res.Value))
+		w.WriteBytes(res.LockingScript)
+	}
+
+	return w.Bytes()
+}
+
+func DeserializeResults(data []byte) TXResults {
+	r := wire.NewReader(data)
+
+	results := TXResults{
+		Height: int(r.ReadUint32()),
+		Time:   r.ReadInt64(),
+	}
+
+	count := r.ReadCount()
+	for i := 0; i < count && r.Err() == nil; i++ {
+		results.Indexes = append(results.Indexes, int(r.ReadVarInt()))
+		results.Results = append(results.Results, TXResult{
+			Value:         int(r.ReadInt64()),
+			LockingScript: r.ReadBytes(),
+		})
+	}
+
+	core.Handle(r.Finish())
+
+	return results
+}
@@ -0,0 +1,216 @@
+package factory
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+func encodingTestTx(version uint32) *Transaction {
+	tx := &Transaction{
+		Version: version,
+		Requests: []TXRequest{
+			{ID: []byte("previous transaction 1"), Out: 0, UnlockingScript: []byte("unlocking 1"), Sequence: 0xffffffff},
+			{ID: []byte("previous transaction 2"), Out: -1, UnlockingScript: []byte("unlocking 2"), Sequence: 7},
+		},
+		Results: []TXResult{
+			{Value: 10, LockingScript: script.PayToPubKeyHash([]byte("recipient 1"))},
+			{Value: 20, LockingScript: script.PayToPubKeyHash([]byte("recipient 2"))},
+		},
+		LockTime: 500,
+	}
+
+	if version == LegacyVersion {
+		tx.ID = []byte("legacy transaction id")
+	} else {
+		tx.ID = tx.CalculateHash()
+	}
+
+	return tx
+}
+
+// mineTestBlock finds a nonce for the block without the progress output of
+// ProofOfWork.Run.
+func mineTestBlock(block *Block) {
+	var hash big.Int
+
+	pow := NewProof(block)
+
+	for nonce := 0; ; nonce++ {
+		data := sha256.Sum256(pow.InitData(nonce))
+
+		if hash.SetBytes(data[:]).Cmp(pow.Target) == -1 {
+			block.Nonce, block.Hash = nonce, data[:]
+
+			return
+		}
+	}
+}
+
+func encodingTestBlock(version uint32) *Block {
+	block := &Block{
+		Version:      version,
+		Transactions: []*Transaction{encodingTestTx(TransactionVersion), encodingTestTx(InlineScriptVersion), encodingTestTx(TransactionVersion)},
+		PreviousHash: []byte("previous block"),
+		Height:       3,
+		Timestamp:    1700000000,
+	}
+	block.Transactions[2].LockTime++
+	block.Transactions[2].ID = block.Transactions[2].CalculateHash()
+
+	mineTestBlock(block)
+
+	return block
+}
+
+func equalTransactions(a, b *Transaction) bool {
+	return fmt.Sprint(a.Version, a.ID, a.Requests, a.Results, a.LockTime) == fmt.Sprint(b.Version, b.ID, b.Requests, b.Results, b.LockTime)
+}
+
+func TestTransactionEncoding(t *testing.T) {
+	for _, version := range []uint32{InlineScriptVersion, WitnessVersion} {
+		tx := encodingTestTx(version)
+
+		decoded, err := DeserializeTransaction(tx.Serialize())
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+
+		if !equalTransactions(decoded, tx) {
+			t.Errorf("version %d: decoded %v, want %v", version, decoded, tx)
+		}
+
+		data := tx.Serialize()
+		for size := 0; size < len(data); size++ {
+			if _, err := DeserializeTransaction(data[:size]); err == nil {
+				t.Errorf("version %d: %d of %d bytes decoded", version, size, len(data))
+			}
+		}
+
+		if _, err := DeserializeTransaction(append(data, 0)); err == nil {
+			t.Errorf("version %d: trailing byte accepted", version)
+		}
+	}
+
+	// Unlocking scripts are outside the ID, not the witness hash.
+	for _, version := range []uint32{InlineScriptVersion, WitnessVersion} {
+		tx, signed := encodingTestTx(version), encodingTestTx(version)
+		signed.Requests[0].UnlockingScript = []byte("other unlocking")
+
+		if !bytes.Equal(signed.CalculateHash(), tx.ID) || bytes.Equal(signed.WitnessHash(), tx.WitnessHash()) {
+			t.Errorf("version %d: unlocking script changes the ID or keeps the witness hash", version)
+		}
+	}
+}
+
+func TestLegacyTransactionEncoding(t *testing.T) {
+	tx := encodingTestTx(LegacyVersion)
+	data := tx.Serialize()
+
+	if _, err := DeserializeTransaction(data); err != core.ErrUnknownVersion {
+		t.Errorf("legacy transaction from the network: %v", err)
+	}
+
+	decoded, err := decodeTransaction(wire.NewReader(data), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equalTransactions(decoded, tx) {
+		t.Errorf("stored legacy transaction decoded as %v", decoded)
+	}
+
+	unknown := encodingTestTx(TransactionVersion)
+	unknown.Version++
+
+	if _, err := DeserializeTransaction(unknown.Serialize()); err != core.ErrUnknownVersion {
+		t.Errorf("version %d: %v", unknown.Version, err)
+	}
+}
+
+func TestHeaderEncoding(t *testing.T) {
+	for _, version := range []uint32{SerializedMerkleVersion, BlockVersion} {
+		block := encodingTestBlock(version)
+		header := block.Header()
+
+		decoded, err := DeserializeHeader(header.Serialize())
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+
+		if !bytes.Equal(decoded.Block.Hash, block.Hash) || !bytes.Equal(decoded.MerkleRoot, header.MerkleRoot) || !bytes.Equal(decoded.WitnessRoot, header.WitnessRoot) {
+			t.Errorf("version %d: header changed on a round trip", version)
+		}
+
+		if !decoded.Validate() {
+			t.Errorf("version %d: decoded header has no proof of work", version)
+		}
+
+		data := header.Serialize()
+		for size := 0; size < len(data); size++ {
+			if _, err := DeserializeHeader(data[:size]); err == nil {
+				t.Errorf("version %d: %d of %d bytes decoded", version, size, len(data))
+			}
+		}
+	}
+
+	legacy := &Block{Version: LegacyVersion, Hash: make([]byte, sha256.Size), PreviousHash: []byte("previous block")}
+	data := legacy.Header().Serialize()
+
+	if _, err := DeserializeHeader(data); err != core.ErrLegacyHeader {
+		t.Errorf("legacy header from the network: %v", err)
+	}
+
+	if header, err := decodeHeader(wire.NewReader(data), true); err != nil || !bytes.Equal(header.Block.Hash, legacy.Hash) {
+		t.Errorf("stored legacy header: %v", err)
+	}
+}
+
+func TestBlockEncoding(t *testing.T) {
+	for _, version := range []uint32{SerializedMerkleVersion, BlockVersion} {
+		block := encodingTestBlock(version)
+		data := block.Serialize()
+
+		decoded, err := DeserializeBlock(data)
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+
+		if !bytes.Equal(decoded.Hash, block.Hash) || decoded.Height != block.Height || len(decoded.Transactions) != len(block.Transactions) {
+			t.Fatalf("version %d: block changed on a round trip", version)
+		}
+
+		for i, tx := range decoded.Transactions {
+			if !equalTransactions(tx, block.Transactions[i]) {
+				t.Errorf("version %d: transaction %d decoded as %v", version, i, tx)
+			}
+		}
+
+		for size := 0; size < len(data); size++ {
+			if _, err := DeserializeBlock(data[:size]); err == nil {
+				t.Errorf("version %d: %d of %d bytes decoded", version, size, len(data))
+			}
+		}
+
+		// The header commits to the transactions.
+		tampered := *block
+		tampered.Transactions = block.Transactions[:2]
+
+		w := wire.NewWriter()
+		block.Header().encode(w)
+		w.WriteVarInt(uint64(len(tampered.Transactions)))
+		for _, tx := range tampered.Transactions {
+			tx.encode(w, true)
+		}
+
+		if _, err := DeserializeBlock(w.Bytes()); err != core.ErrInvalidBlock {
+			t.Errorf("version %d: block without a transaction: %v", version, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package gcs
+
+// bitWriter appends bits most significant first.
+type bitWriter struct {
+	data []byte
+	used uint8
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.used == 0 {
+		w.data = append(w.data, 0)
+		w.used = 8
+	}
+
+	w.used--
+	if bit {
+		w.data[len(w.data)-1] |= 1 << w.used
+	}
+}
+
+func (w *bitWriter) writeBits(value uint64, count uint8) {
+	for count > 0 {
+		count--
+		w.writeBit(value&(1<<count) != 0)
+	}
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) readBit() (bool, bool) {
+	if r.pos >= len(r.data)*8 {
+		return false, false
+	}
+
+	bit := r.data[r.pos/8]&(0x80>>(r.pos%8)) != 0
+	r.pos++
+
+	return bit, true
+}
+
+func (r *bitReader) readBits(count uint8) (uint64, bool) {
+	var value uint64
+
+	for ; count > 0; count-- {
+		bit, ok := r.readBit()
+		if !ok {
+			return 0, false
+		}
+
+		value <<= 1
+		if bit {
+			value |= 1
+		}
+	}
+
+	return value, true
+}
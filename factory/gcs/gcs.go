@@ -0,0 +1,179 @@
+// Package gcs builds Golomb-coded sets as in BIP158: compact filters a light
+// client tests its items against, with false positives but no false
+// negatives, without telling anyone which items it is after.
+package gcs
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+	"sort"
+
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+// P is the Golomb-Rice parameter and M the inverse of the false positive
+// rate, the values of the BIP158 basic filter.
+const (
+	P = 19
+	M = 784931
+
+	KeySize = 16
+)
+
+// Filter is the set of the N items hashed with a key, usually taken from the
+// hash of the block they belong to.
+type Filter struct {
+	N    uint32
+	Data []byte
+}
+
+// hashItem maps an item uniformly to [0, n*M).
+func hashItem(key []byte, item []byte, n uint32) uint64 {
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+
+	hi, _ := bits.Mul64(sipHash(k0, k1, item), uint64(n)*M)
+
+	return hi
+}
+
+// Build hashes the items, duplicates counted once, with the first KeySize
+// bytes of key.
+func Build(key []byte, items [][]byte) *Filter {
+	unique := make(map[string]bool)
+	for _, item := range items {
+		unique[string(item)] = true
+	}
+
+	filter := &Filter{N: uint32(len(unique))}
+
+	var values []uint64
+	for item := range unique {
+		values = append(values, hashItem(key, []byte(item), filter.N))
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	w := &bitWriter{}
+
+	var last uint64
+	for _, value := range values {
+		delta := value - last
+		last = value
+
+		for q := delta >> P; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, P)
+	}
+
+	filter.Data = w.data
+
+	return filter
+}
+
+// MatchAny tells if one of the items may be in the filter.
+func (filter *Filter) MatchAny(key []byte, items [][]byte) bool {
+	if filter.N == 0 || len(items) == 0 {
+		return false
+	}
+
+	var targets []uint64
+	for _, item := range items {
+		targets = append(targets, hashItem(key, item, filter.N))
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+
+	r := &bitReader{data: filter.Data}
+
+	var value uint64
+	for i := uint32(0); i < filter.N; i++ {
+		var q uint64
+		for {
+			bit, ok := r.readBit()
+			if !ok {
+				return false
+			}
+			if !bit {
+				break
+			}
+			q++
+		}
+
+		remainder, ok := r.readBits(P)
+		if !ok {
+			return false
+		}
+
+		value += q<<P | remainder
+
+		for len(targets) > 0 && targets[0] < value {
+			targets = targets[1:]
+		}
+
+		if len(targets) == 0 {
+			return false
+		}
+
+		if targets[0] == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (filter *Filter) Match(key []byte, item []byte) bool {
+	return filter.MatchAny(key, [][]byte{item})
+}
+
+// Serialize writes N as a varint followed by the bits of the set.
+func (filter *Filter) Serialize() []byte {
+	w := wire.NewWriter()
+	w.WriteVarInt(uint64(filter.N))
+	w.WriteRaw(filter.Data)
+
+	return w.Bytes()
+}
+
+func Deserialize(data []byte) (*Filter, error) {
+	r := wire.NewReader(data)
+
+	n := r.ReadCount()
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	prefix := wire.NewWriter()
+	prefix.WriteVarInt(uint64(n))
+
+	return &Filter{
+		N:    uint32(n),
+		Data: data[len(prefix.Bytes()):],
+	}, nil
+}
+
+// Hash is the double SHA-256 of the serialized filter.
+func (filter *Filter) Hash() []byte {
+	first := sha256.Sum256(filter.Serialize())
+	second := sha256.Sum256(first[:])
+
+	return second[:]
+}
+
+// Header chains the filter to the header of the previous block's filter, so
+// a client holding the headers can check any filter it downloads. The
+// previous header of the genesis filter is all zeros.
+func (filter *Filter) Header(previous []byte) []byte {
+	if len(previous) == 0 {
+		previous = make([]byte, sha256.Size)
+	}
+
+	first := sha256.Sum256(append(filter.Hash(), previous...))
+	second := sha256.Sum256(first[:])
+
+	return second[:]
+}
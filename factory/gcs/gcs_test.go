@@ -0,0 +1,159 @@
+package gcs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+// Vectors of the SipHash-2-4 reference implementation: key 00..0f and
+// messages 00..n-1.
+func TestSipHash(t *testing.T) {
+	tests := []struct {
+		length int
+		hash   uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{1, 0x74f839c593dc67fd},
+		{2, 0x0d6c8009d9a94f5a},
+		{3, 0x85676696d7fb7e2d},
+		{4, 0xcf2794e0277187b7},
+		{7, 0xab0200f58b01d137},
+		{8, 0x93f5f5799a932462},
+		{15, 0xa129ca6149be45e5},
+	}
+
+	key := make([]byte, KeySize)
+	message := make([]byte, 15)
+	for i := range key {
+		key[i] = byte(i)
+		if i < len(message) {
+			message[i] = byte(i)
+		}
+	}
+
+	k0, k1 := binary.LittleEndian.Uint64(key[:8]), binary.LittleEndian.Uint64(key[8:])
+
+	for _, test := range tests {
+		if hash := sipHash(k0, k1, message[:test.length]); hash != test.hash {
+			t.Errorf("%d bytes: %#016x, want %#016x", test.length, hash, test.hash)
+		}
+	}
+}
+
+func testItems(prefix string, n int) [][]byte {
+	var items [][]byte
+
+	for i := 0; i < n; i++ {
+		items = append(items, []byte(fmt.Sprintf("%s %d", prefix, i)))
+	}
+
+	return items
+}
+
+func TestMatch(t *testing.T) {
+	key := []byte("0123456789abcdef")
+
+	for _, n := range []int{1, 2, 10, 100} {
+		items := testItems("item", n)
+		filter := Build(key, items)
+
+		if filter.N != uint32(n) {
+			t.Errorf("%d items: N is %d", n, filter.N)
+		}
+
+		for _, item := range items {
+			if !filter.Match(key, item) {
+				t.Errorf("%d items: %s does not match", n, item)
+			}
+		}
+
+		others := testItems("other", 1000)
+		for _, other := range others {
+			if filter.Match(key, other) {
+				t.Errorf("%d items: %s matches", n, other)
+			}
+		}
+
+		if filter.MatchAny(key, others) {
+			t.Errorf("%d items: other items match", n)
+		}
+
+		if !filter.MatchAny(key, append(others, items[n-1])) {
+			t.Errorf("%d items: other items with %s don't match", n, items[n-1])
+		}
+
+		if filter.Match([]byte("fedcba9876543210"), items[0]) && filter.Match([]byte("fedcba9876543210"), items[n-1]) {
+			t.Errorf("%d items: match under another key", n)
+		}
+	}
+}
+
+func TestEmptyAndDuplicates(t *testing.T) {
+	key := []byte("0123456789abcdef")
+
+	empty := Build(key, nil)
+	if empty.N != 0 || empty.Match(key, []byte("item")) || empty.MatchAny(key, nil) {
+		t.Error("empty filter matches")
+	}
+
+	items := testItems("item", 3)
+	duplicated := Build(key, append(items, items...))
+
+	if duplicated.N != 3 || !bytes.Equal(duplicated.Serialize(), Build(key, items).Serialize()) {
+		t.Error("duplicate items are counted twice")
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	filter := Build(key, testItems("item", 20))
+
+	decoded, err := Deserialize(filter.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.N != filter.N || !bytes.Equal(decoded.Data, filter.Data) || !bytes.Equal(decoded.Hash(), filter.Hash()) {
+		t.Error("filter changed on a round trip")
+	}
+
+	if _, err := Deserialize(nil); err == nil {
+		t.Error("filter without N decoded")
+	}
+
+	truncated := &Filter{N: decoded.N}
+	if truncated.MatchAny(key, testItems("item", 20)) {
+		t.Error("filter without data matches")
+	}
+}
+
+// Vector 0 of BIP158: the testnet genesis block, whose only item is the
+// locking script of its coinbase.
+func TestBIP158Genesis(t *testing.T) {
+	decode := func(data string) []byte {
+		decoded, err := hex.DecodeString(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return decoded
+	}
+
+	blockHash := decode("43497fd7f826957108f4a30fd9cec3aeba79972084e90ead01ea330900000000")
+	lockingScript := decode("4104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac")
+
+	filter := Build(blockHash[:KeySize], [][]byte{lockingScript})
+
+	if serialized := hex.EncodeToString(filter.Serialize()); serialized != "019dfca8" {
+		t.Errorf("filter %s, want 019dfca8", serialized)
+	}
+
+	// The BIP prints headers byte reversed.
+	header := hex.EncodeToString(filter.Header(nil))
+	if header != "50b781aed7b7129012a6d20e2d040027937f3affaee573779908ebb779455821" {
+		t.Errorf("header %s", header)
+	}
+}
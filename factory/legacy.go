@@ -0,0 +1,160 @@
+package factory
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/script"
+)
+
+// The legacy types mirror every gob layout blocks were stored with. gob
+// matches fields by name, so the fields of the first releases (Signature,
+// PubKey and PubKeyHash) decode next to the script based ones.
+type legacyBlock struct {
+	Hash         []byte
+	Transactions []*legacyTransaction
+	PreviousHash []byte
+	Nonce        int
+	Height       int
+	Timestamp    int64
+}
+
+type legacyTransaction struct {
+	ID       []byte
+	Requests []legacyRequest
+	Results  []legacyResult
+	LockTime int64
+}
+
+type legacyRequest struct {
+	ID              []byte
+	Out             int
+	UnlockingScript []byte
+	Sequence        uint32
+	Signature       []byte
+	PubKey          []byte
+}
+
+type legacyResult struct {
+	Value         int
+	LockingScript []byte
+	PubKeyHash    []byte
+}
+
+func (legacy *legacyTransaction) convert() *Transaction {
+	tx := &Transaction{
+		Version:  LegacyVersion,
+		ID:       legacy.ID,
+		LockTime: legacy.LockTime,
+	}
+
+	for _, req := range legacy.Requests {
+		unlocking := req.UnlockingScript
+
+		if unlocking == nil && req.Out == -1 {
+			unlocking = req.PubKey
+		} else if unlocking == nil && req.Signature != nil {
+			unlocking = script.UnlockPubKeyHash(req.Signature, req.PubKey)
+		}
+
+		tx.Requests = append(tx.Requests, TXRequest{
+			ID:              req.ID,
+			Out:             req.Out,
+			UnlockingScript: unlocking,
+			Sequence:        req.Sequence,
+		})
+	}
+
+	for _, res := range legacy.Results {
+		locking := res.LockingScript
+		if locking == nil && res.PubKeyHash != nil {
+			locking = script.PayToPubKeyHash(res.PubKeyHash)
+		}
+
+		tx.Results = append(tx.Results, TXResult{
+			Value:         res.Value,
+			LockingScript: locking,
+		})
+	}
+
+	return tx
+}
+
+func decodeLegacyBlock(data []byte) (*Block, error) {
+	var legacy legacyBlock
+
+	decoder := gob.NewDecoder(bytes.NewBuffer(data))
+	if err := decoder.Decode(&legacy); err != nil {
+		return nil, err
+	}
+
+	block := &Block{
+		Version:      LegacyVersion,
+		Hash:         legacy.Hash,
+		PreviousHash: legacy.PreviousHash,
+		Nonce:        legacy.Nonce,
+		Height:       legacy.Height,
+		Timestamp:    legacy.Timestamp,
+	}
+
+	for _, tx := range legacy.Transactions {
+		block.Transactions = append(block.Transactions, tx.convert())
+	}
+
+	return block, nil
+}
+
+// MigrateLegacyBlocks rewrites the gob encoded blocks of the chain in the
+// wire format, keeping their hashes and transaction IDs, and returns how many
+// it converted. The first releases didn't store heights, so legacy blocks get
+// the one of their place from the genesis. The UTXO set is left to be
+// reindexed.
+func (chain *Blockchain) MigrateLegacyBlocks() int {
+	var blocks []*Block
+	var legacy []bool
+
+	for hash := chain.LastHash; len(hash) > 0; {
+		data, err := chain.Database.Get(hash)
+		core.Handle(err)
+
+		var block *Block
+		if isLegacyEncoding(data) {
+			block, err = decodeLegacyBlock(data)
+		} else {
+			block, err = deserializeStoredBlock(data)
+		}
+		core.Handle(err)
+
+		blocks = append(blocks, block)
+		legacy = append(legacy, isLegacyEncoding(data))
+
+		hash = block.PreviousHash
+	}
+
+	batch := chain.Database.NewBatch()
+	migrated := 0
+
+	for i, block := range blocks {
+		height := len(blocks) - 1 - i
+
+		if block.Version == LegacyVersion && block.Height != height {
+			block.Height = height
+		} else if !legacy[i] {
+			continue
+		}
+
+		batch.Put(block.Hash, block.Serialize())
+		migrated++
+	}
+
+	// The unspent outputs carry the heights of their blocks.
+	if migrated > 0 {
+		batch.Delete([]byte(utxoTipKey))
+	}
+
+	err := chain.Database.Write(batch)
+	core.Handle(err)
+
+	return migrated
+}
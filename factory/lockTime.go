@@ -0,0 +1,75 @@
+package factory
+
+const (
+	// LockTimeThreshold splits lock times: below it they are block heights,
+	// otherwise unix timestamps.
+	LockTimeThreshold = 500000000
+
+	SequenceFinal = uint32(0xffffffff)
+
+	// A request sequence below SequenceLockTimeDisabled is a relative lock:
+	// the result it spends must be buried for SequenceLockTimeMask blocks, or
+	// for that many 512 seconds units when SequenceLockTimeIsSeconds is set.
+	SequenceLockTimeDisabled    = uint32(1 << 31)
+	SequenceLockTimeIsSeconds   = uint32(1 << 22)
+	SequenceLockTimeMask        = uint32(0x0000ffff)
+	SequenceLockTimeGranularity = 9
+)
+
+// IsFinal reports whether tx can be included in a block at height with the
+// given timestamp.
+func (tx *Transaction) IsFinal(height int, blockTime int64) bool {
+	if tx.LockTime == 0 || LockTimeReached(tx.LockTime, height, blockTime) {
+		return true
+	}
+
+	for _, req := range tx.Requests {
+		if req.Sequence != SequenceFinal {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LockTimeReached reports whether a block at height with the given timestamp
+// is past lockTime.
+func LockTimeReached(lockTime int64, height int, blockTime int64) bool {
+	if lockTime >= LockTimeThreshold {
+		return lockTime < blockTime
+	}
+
+	return lockTime < int64(height)
+}
+
+// SequenceLockMet reports whether the relative lock of a request is satisfied
+// in a block at height with the given timestamp, given where the spent
+// result was confirmed.
+func SequenceLockMet(sequence uint32, prevHeight int, prevTime int64, height int, blockTime int64) bool {
+	if sequence&SequenceLockTimeDisabled != 0 {
+		return true
+	}
+
+	value := int64(sequence & SequenceLockTimeMask)
+
+	if sequence&SequenceLockTimeIsSeconds != 0 {
+		return blockTime-prevTime >= value<<SequenceLockTimeGranularity
+	}
+
+	return int64(height-prevHeight) >= value
+}
+
+// checkLockTime applies the rules of OP_CHECKLOCKTIMEVERIFY to the request at
+// reqId: the script lock time must be of the same kind as the transaction
+// lock time, not above it, and the request must not opt out of lock times.
+func (tx *Transaction) checkLockTime(reqId int, lockTime int64) bool {
+	if (lockTime < LockTimeThreshold) != (tx.LockTime < LockTimeThreshold) {
+		return false
+	}
+
+	if lockTime > tx.LockTime {
+		return false
+	}
+
+	return tx.Requests[reqId].Sequence != SequenceFinal
+}
@@ -1,17 +1,50 @@
 package merkle
 
-import "crypto/sha256"
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// Leaves and inner nodes hash with distinct prefixes, so an inner node can't
+// be passed off as a leaf.
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+func hashLeaf(data []byte, legacy bool) []byte {
+	if legacy {
+		hash := sha256.Sum256(data)
+		return hash[:]
+	}
+
+	hash := sha256.Sum256(append([]byte{leafPrefix}, data...))
+
+	return hash[:]
+}
+
+func hashPair(left, right []byte, legacy bool) []byte {
+	var pair []byte
+
+	if !legacy {
+		pair = append(pair, nodePrefix)
+	}
+
+	pair = append(append(pair, left...), right...)
+	hash := sha256.Sum256(pair)
+
+	return hash[:]
+}
 
 func NewMerkleNode(left, right *MerkleNode, data []byte) *MerkleNode {
 	node := MerkleNode{}
 
 	if left == nil && right == nil {
-		hash := sha256.Sum256(data)
-		node.Data = hash[:]
+		node.Data = hashLeaf(data, false)
 	} else {
-		prevHash := append(left.Data, right.Data...)
-		hash := sha256.Sum256(prevHash)
-		node.Data = hash[:]
+		node.Data = hashPair(left.Data, right.Data, false)
 	}
 
 	node.Left = left
@@ -20,30 +53,178 @@ func NewMerkleNode(left, right *MerkleNode, data []byte) *MerkleNode {
 	return &node
 }
 
+// NewMerkleTree builds the whole tree, Root is enough when only the root is
+// needed. The last node of an odd level moves up unchanged.
 func NewMerkleTree(data [][]byte) *MerkleTree {
-	var nodes []MerkleNode
-
-	if len(data)%2 != 0 {
-		data = append(data, data[len(data)-1])
+	if len(data) == 0 {
+		return &MerkleTree{}
 	}
 
+	var nodes []*MerkleNode
+
 	for _, dat := range data {
-		node := NewMerkleNode(nil, nil, dat)
-		nodes = append(nodes, *node)
+		nodes = append(nodes, NewMerkleNode(nil, nil, dat))
 	}
 
-	for i := 0; i < len(data)/2; i++ {
-		var level []MerkleNode
+	for len(nodes) > 1 {
+		var level []*MerkleNode
 
 		for j := 0; j < len(nodes); j += 2 {
-			node := NewMerkleNode(&nodes[j], &nodes[j+1], nil)
-			level = append(level, *node)
+			if j+1 == len(nodes) {
+				level = append(level, nodes[j])
+				continue
+			}
+
+			level = append(level, NewMerkleNode(nodes[j], nodes[j+1], nil))
 		}
 
 		nodes = level
 	}
 
-	tree := &MerkleTree{&nodes[0]}
+	return &MerkleTree{nodes[0]}
+}
+
+// Root is the merkle root of the leaves, nil without leaves.
+func Root(data [][]byte) []byte {
+	return root(data, false)
+}
+
+// LegacyRoot is the root of the trees of the first blocks: no prefixes and
+// odd levels, the leaves included, duplicate their last node.
+func LegacyRoot(data [][]byte) []byte {
+	return root(padLegacy(data), true)
+}
+
+func root(data [][]byte, legacy bool) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+
+	level := make([][]byte, len(data))
+	for i, dat := range data {
+		level[i] = hashLeaf(dat, legacy)
+	}
+
+	for len(level) > 1 {
+		level = parentLevel(level, legacy)
+	}
+
+	return level[0]
+}
+
+func parentLevel(level [][]byte, legacy bool) [][]byte {
+	var parents [][]byte
+
+	for j := 0; j < len(level); j += 2 {
+		switch {
+		case j+1 < len(level):
+			parents = append(parents, hashPair(level[j], level[j+1], legacy))
+		case legacy:
+			parents = append(parents, hashPair(level[j], level[j], legacy))
+		default:
+			parents = append(parents, level[j])
+		}
+	}
+
+	return parents
+}
+
+// padLegacy repeats the last leaf of an odd list, which the legacy trees did
+// even for a single leaf.
+func padLegacy(data [][]byte) [][]byte {
+	if len(data)%2 == 0 {
+		return data
+	}
+
+	return append(data[:len(data):len(data)], data[len(data)-1])
+}
+
+func NewProof(data [][]byte, index int) (*Proof, error) {
+	return newProof(data, index, false)
+}
+
+func NewLegacyProof(data [][]byte, index int) (*Proof, error) {
+	return newProof(padLegacy(data), index, true)
+}
+
+func newProof(data [][]byte, index int, legacy bool) (*Proof, error) {
+	if index < 0 || index >= len(data) {
+		return nil, core.ErrLeafNotFound
+	}
+
+	level := make([][]byte, len(data))
+	for i, dat := range data {
+		level[i] = hashLeaf(dat, legacy)
+	}
+
+	proof := &Proof{Index: index, Leaves: len(data), Legacy: legacy}
+
+	for position := index; len(level) > 1; position /= 2 {
+		switch {
+		case position^1 < len(level):
+			proof.Siblings = append(proof.Siblings, level[position^1])
+		case legacy:
+			proof.Siblings = append(proof.Siblings, level[position])
+		}
+
+		level = parentLevel(level, legacy)
+	}
+
+	return proof, nil
+}
+
+// Root is the merkle root the proof leads to from the leaf data, nil when
+// the proof doesn't fit a tree of its number of leaves.
+func (proof *Proof) Root(data []byte) []byte {
+	if proof.Index < 0 || proof.Index >= proof.Leaves {
+		return nil
+	}
+
+	hash := hashLeaf(data, proof.Legacy)
+	siblings := proof.Siblings
+
+	for position, size := proof.Index, proof.Leaves; size > 1; position, size = position/2, (size+1)/2 {
+		if position^1 >= size && !proof.Legacy {
+			continue
+		}
+
+		if len(siblings) == 0 {
+			return nil
+		}
+
+		if position%2 == 0 {
+			hash = hashPair(hash, siblings[0], proof.Legacy)
+		} else {
+			hash = hashPair(siblings[0], hash, proof.Legacy)
+		}
+
+		siblings = siblings[1:]
+	}
+
+	if len(siblings) != 0 {
+		return nil
+	}
+
+	return hash
+}
+
+// Path tells for each sibling, from the leaves up, if it is on the left.
+func (proof *Proof) Path() []bool {
+	var path []bool
+
+	for position, size := proof.Index, proof.Leaves; size > 1 && len(path) < len(proof.Siblings); position, size = position/2, (size+1)/2 {
+		if position^1 >= size && !proof.Legacy {
+			continue
+		}
+
+		path = append(path, position%2 == 1)
+	}
+
+	return path
+}
+
+func VerifyProof(root, data []byte, proof *Proof) bool {
+	hash := proof.Root(data)
 
-	return tree
+	return hash != nil && bytes.Equal(hash, root)
 }
@@ -0,0 +1,177 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+func testLeaves(n int) [][]byte {
+	var data [][]byte
+
+	for i := 0; i < n; i++ {
+		data = append(data, []byte(fmt.Sprintf("leaf %d", i)))
+	}
+
+	return data
+}
+
+func sha(parts ...[]byte) []byte {
+	hash := sha256.Sum256(bytes.Join(parts, nil))
+
+	return hash[:]
+}
+
+// referenceRoot splits the leaves at the largest power of two below their
+// count, which builds the same tree as carrying odd nodes up.
+func referenceRoot(data [][]byte) []byte {
+	if len(data) == 1 {
+		return sha([]byte{leafPrefix}, data[0])
+	}
+
+	split := 1
+	for split*2 < len(data) {
+		split *= 2
+	}
+
+	return sha([]byte{nodePrefix}, referenceRoot(data[:split]), referenceRoot(data[split:]))
+}
+
+// referenceLegacyRoot duplicates the last hash of every odd level, the
+// leaves included.
+func referenceLegacyRoot(data [][]byte) []byte {
+	var level [][]byte
+	for _, dat := range data {
+		level = append(level, sha(dat))
+	}
+
+	for {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		var parents [][]byte
+		for i := 0; i < len(level); i += 2 {
+			parents = append(parents, sha(level[i], level[i+1]))
+		}
+
+		level = parents
+		if len(level) == 1 {
+			return level[0]
+		}
+	}
+}
+
+func TestRoot(t *testing.T) {
+	if Root(nil) != nil || NewMerkleTree(nil).RootNode != nil {
+		t.Fatal("empty tree has a root")
+	}
+
+	for n := 1; n <= 7; n++ {
+		data := testLeaves(n)
+
+		if root := Root(data); !bytes.Equal(root, referenceRoot(data)) {
+			t.Errorf("%d leaves: root %x, want %x", n, root, referenceRoot(data))
+		}
+
+		if root := NewMerkleTree(data).RootNode.Data; !bytes.Equal(root, Root(data)) {
+			t.Errorf("%d leaves: tree root %x, want %x", n, root, Root(data))
+		}
+
+		if root := LegacyRoot(data); !bytes.Equal(root, referenceLegacyRoot(data)) {
+			t.Errorf("%d leaves: legacy root %x, want %x", n, root, referenceLegacyRoot(data))
+		}
+	}
+}
+
+// The last leaf of an odd count must not give the root of the list with it
+// repeated, in either kind of tree.
+func TestRootDuplicateLeaf(t *testing.T) {
+	data := testLeaves(3)
+	padded := append(testLeaves(3), data[2])
+
+	if bytes.Equal(Root(data), Root(padded)) {
+		t.Error("repeating the last leaf keeps the root")
+	}
+
+	// Legacy trees have the CVE-2012-2459 ambiguity, PartialTree.Extract
+	// rejects it.
+	if !bytes.Equal(LegacyRoot(data), LegacyRoot(padded)) {
+		t.Error("legacy trees don't pad odd levels")
+	}
+}
+
+func TestProofs(t *testing.T) {
+	for n := 1; n <= 7; n++ {
+		data := testLeaves(n)
+
+		for _, legacy := range []bool{false, true} {
+			root, newProof := Root(data), NewProof
+			if legacy {
+				root, newProof = LegacyRoot(data), NewLegacyProof
+			}
+
+			for index := 0; index < n; index++ {
+				name := fmt.Sprintf("%d leaves legacy %t index %d", n, legacy, index)
+
+				proof, err := newProof(data, index)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+
+				if !VerifyProof(root, data[index], proof) {
+					t.Errorf("%s: proof does not verify", name)
+				}
+
+				if len(proof.Path()) != len(proof.Siblings) {
+					t.Errorf("%s: path of %d sides for %d siblings", name, len(proof.Path()), len(proof.Siblings))
+				}
+
+				if VerifyProof(root, []byte("other leaf"), proof) {
+					t.Errorf("%s: proof verifies another leaf", name)
+				}
+
+				if len(proof.Siblings) > 0 {
+					tampered := *proof
+					tampered.Siblings = append([][]byte{sha([]byte("tampered"))}, proof.Siblings[1:]...)
+
+					if VerifyProof(root, data[index], &tampered) {
+						t.Errorf("%s: tampered proof verifies", name)
+					}
+
+					truncated := *proof
+					truncated.Siblings = proof.Siblings[1:]
+
+					if VerifyProof(root, data[index], &truncated) {
+						t.Errorf("%s: truncated proof verifies", name)
+					}
+				}
+
+				if n > 1 {
+					moved := *proof
+					moved.Index = (index + 1) % n
+
+					if VerifyProof(root, data[index], &moved) && !bytes.Equal(data[moved.Index], data[index]) {
+						t.Errorf("%s: proof verifies at index %d", name, moved.Index)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestProofOutOfRange(t *testing.T) {
+	data := testLeaves(3)
+
+	for _, index := range []int{-1, 3} {
+		if _, err := NewProof(data, index); err == nil {
+			t.Errorf("index %d: proof built", index)
+		}
+	}
+
+	proof := &Proof{Index: 3, Leaves: 3}
+	if proof.Root(data[0]) != nil {
+		t.Error("proof past the last leaf has a root")
+	}
+}
@@ -9,3 +9,13 @@ type MerkleNode struct {
 	Right *MerkleNode
 	Data  []byte
 }
+
+// Proof is the merkle path of a leaf: the hashes of its siblings from the
+// leaves up to the root. Index and the number of leaves tell on which side
+// each sibling is and which levels carry the node up without one.
+type Proof struct {
+	Index    int
+	Leaves   int
+	Siblings [][]byte
+	Legacy   bool
+}
@@ -0,0 +1,165 @@
+package merkle
+
+import (
+	"bytes"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// PartialTree is the BIP37 partial merkle tree: the pruned tree of a block
+// that keeps the path of some matched leaves. Walking it depth first, a flag
+// tells if a node is above a match, and the hash of every leaf and of every
+// node not above a match is given.
+type PartialTree struct {
+	Leaves int
+	Hashes [][]byte
+	Flags  []bool
+	Legacy bool
+}
+
+// LeafHash is the hash the tree keeps for the leaf data.
+func LeafHash(data []byte, legacy bool) []byte {
+	return hashLeaf(data, legacy)
+}
+
+// width is the number of nodes of the level at height in a tree of leaves.
+func width(leaves, height int) int {
+	return (leaves + (1 << height) - 1) >> height
+}
+
+// height is the height of the root. A legacy tree hashes even a single leaf
+// with itself.
+func (tree *PartialTree) height() int {
+	height := 0
+	for width(tree.Leaves, height) > 1 || (tree.Legacy && height == 0) {
+		height++
+	}
+
+	return height
+}
+
+func NewPartialTree(data [][]byte, matches []bool) *PartialTree {
+	return newPartialTree(data, matches, false)
+}
+
+// NewLegacyPartialTree leaves the padding leaf of odd lists out: duplicating
+// the last node of the leaf level gives the same root.
+func NewLegacyPartialTree(data [][]byte, matches []bool) *PartialTree {
+	return newPartialTree(data, matches, true)
+}
+
+func newPartialTree(data [][]byte, matches []bool, legacy bool) *PartialTree {
+	tree := &PartialTree{Leaves: len(data), Legacy: legacy}
+	if len(data) == 0 {
+		return tree
+	}
+
+	levels := [][][]byte{make([][]byte, len(data))}
+	for i, dat := range data {
+		levels[0][i] = hashLeaf(dat, legacy)
+	}
+
+	for len(levels) <= tree.height() {
+		levels = append(levels, parentLevel(levels[len(levels)-1], legacy))
+	}
+
+	var build func(height, position int)
+	build = func(height, position int) {
+		aboveMatch := false
+		for i := position << height; i < (position+1)<<height && i < len(data); i++ {
+			aboveMatch = aboveMatch || matches[i]
+		}
+
+		tree.Flags = append(tree.Flags, aboveMatch)
+
+		if height == 0 || !aboveMatch {
+			tree.Hashes = append(tree.Hashes, levels[height][position])
+			return
+		}
+
+		build(height-1, position*2)
+		if position*2+1 < width(tree.Leaves, height-1) {
+			build(height-1, position*2+1)
+		}
+	}
+
+	build(tree.height(), 0)
+
+	return tree
+}
+
+// Extract walks the tree back to its root and returns it with the hashes and
+// indexes of the matched leaves. Every hash and flag has to be used.
+func (tree *PartialTree) Extract() ([]byte, [][]byte, []int, error) {
+	var matched [][]byte
+	var indexes []int
+
+	if tree.Leaves == 0 || len(tree.Hashes) > tree.Leaves {
+		return nil, nil, nil, core.ErrInvalidProof
+	}
+
+	hashes, flags := tree.Hashes, tree.Flags
+
+	var extract func(height, position int) ([]byte, error)
+	extract = func(height, position int) ([]byte, error) {
+		if len(flags) == 0 {
+			return nil, core.ErrInvalidProof
+		}
+
+		aboveMatch := flags[0]
+		flags = flags[1:]
+
+		if height == 0 || !aboveMatch {
+			if len(hashes) == 0 {
+				return nil, core.ErrInvalidProof
+			}
+
+			hash := hashes[0]
+			hashes = hashes[1:]
+
+			if height == 0 && aboveMatch {
+				matched = append(matched, hash)
+				indexes = append(indexes, position)
+			}
+
+			return hash, nil
+		}
+
+		left, err := extract(height-1, position*2)
+		if err != nil {
+			return nil, err
+		}
+
+		if position*2+1 >= width(tree.Leaves, height-1) {
+			if tree.Legacy {
+				return hashPair(left, left, true), nil
+			}
+
+			return left, nil
+		}
+
+		right, err := extract(height-1, position*2+1)
+		if err != nil {
+			return nil, err
+		}
+
+		// Legacy trees duplicate odd nodes, so equal siblings could stand
+		// for a transaction that isn't there (CVE-2012-2459).
+		if tree.Legacy && bytes.Equal(left, right) {
+			return nil, core.ErrInvalidProof
+		}
+
+		return hashPair(left, right, tree.Legacy), nil
+	}
+
+	root, err := extract(tree.height(), 0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(hashes) != 0 || len(flags) != 0 {
+		return nil, nil, nil, core.ErrInvalidProof
+	}
+
+	return root, matched, indexes, nil
+}
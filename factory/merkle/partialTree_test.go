@@ -0,0 +1,113 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func matchPatterns(n int) [][]bool {
+	var patterns [][]bool
+
+	none, all, ends := make([]bool, n), make([]bool, n), make([]bool, n)
+	for i := range all {
+		all[i] = true
+	}
+	ends[0], ends[n-1] = true, true
+
+	patterns = append(patterns, none, all, ends)
+
+	for i := 0; i < n; i++ {
+		single := make([]bool, n)
+		single[i] = true
+		patterns = append(patterns, single)
+	}
+
+	return patterns
+}
+
+func TestPartialTree(t *testing.T) {
+	for n := 1; n <= 7; n++ {
+		data := testLeaves(n)
+
+		for _, legacy := range []bool{false, true} {
+			root, newTree := Root(data), NewPartialTree
+			if legacy {
+				root, newTree = LegacyRoot(data), NewLegacyPartialTree
+			}
+
+			for _, matches := range matchPatterns(n) {
+				name := fmt.Sprintf("%d leaves legacy %t matches %v", n, legacy, matches)
+
+				extracted, matched, indexes, err := newTree(data, matches).Extract()
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+
+				if !bytes.Equal(extracted, root) {
+					t.Errorf("%s: root %x, want %x", name, extracted, root)
+				}
+
+				var wantIndexes []int
+				for i, match := range matches {
+					if match {
+						wantIndexes = append(wantIndexes, i)
+					}
+				}
+
+				if fmt.Sprint(indexes) != fmt.Sprint(wantIndexes) {
+					t.Errorf("%s: indexes %v, want %v", name, indexes, wantIndexes)
+				}
+
+				for i, index := range indexes {
+					if !bytes.Equal(matched[i], LeafHash(data[index], legacy)) {
+						t.Errorf("%s: hash of leaf %d is %x", name, index, matched[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPartialTreeMalformed(t *testing.T) {
+	data := testLeaves(5)
+	matches := []bool{false, true, false, false, true}
+
+	tests := []struct {
+		name   string
+		change func(tree *PartialTree)
+	}{
+		{"no leaves", func(tree *PartialTree) { tree.Leaves = 0 }},
+		{"extra hash", func(tree *PartialTree) { tree.Hashes = append(tree.Hashes, tree.Hashes[0]) }},
+		{"missing hash", func(tree *PartialTree) { tree.Hashes = tree.Hashes[1:] }},
+		{"extra flag", func(tree *PartialTree) { tree.Flags = append(tree.Flags, false) }},
+		{"missing flag", func(tree *PartialTree) { tree.Flags = tree.Flags[:len(tree.Flags)-1] }},
+		{"more hashes than leaves", func(tree *PartialTree) {
+			for len(tree.Hashes) <= tree.Leaves {
+				tree.Hashes = append(tree.Hashes, tree.Hashes[0])
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		tree := NewPartialTree(data, matches)
+		test.change(tree)
+
+		if _, _, _, err := tree.Extract(); err == nil {
+			t.Errorf("%s: extracted", test.name)
+		}
+	}
+}
+
+// A legacy tree of three leaves has the root of the same leaves with the last
+// repeated, extracting must not match the repeated one (CVE-2012-2459).
+func TestLegacyPartialTreeDuplicate(t *testing.T) {
+	data := testLeaves(3)
+	padded := append(testLeaves(3), data[2])
+
+	tree := NewLegacyPartialTree(padded, []bool{false, false, false, true})
+
+	if _, _, _, err := tree.Extract(); err == nil {
+		t.Error("tree matching a repeated leaf was extracted")
+	}
+}
@@ -0,0 +1,214 @@
+package factory
+
+import (
+	"bytes"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/bloom"
+	"github.com/wilmacedo/willchain-go/factory/merkle"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+// MerkleBlock is what a bloom filtering peer gets for a block: the header,
+// the partial merkle tree of the matched transactions and the transactions
+// themselves.
+type MerkleBlock struct {
+	Header       *Header
+	Tree         *merkle.PartialTree
+	Transactions []*Transaction
+}
+
+func pushedData(code []byte) [][]byte {
+	var data [][]byte
+
+	instructions, err := script.Parse(code)
+	if err != nil {
+		return nil
+	}
+
+	for _, instruction := range instructions {
+		if len(instruction.Data) > 0 {
+			data = append(data, instruction.Data)
+		}
+	}
+
+	return data
+}
+
+// MatchesBloom tells if the transaction matches the filter as in BIP37: its
+// ID, data pushed by a locking script, a spent outpoint or data pushed by an
+// unlocking script. Matched results are added to a filter with UpdateAll.
+func (tx *Transaction) MatchesBloom(filter *bloom.Filter) bool {
+	matched := filter.Matches(tx.ID)
+
+	for resId, res := range tx.Results {
+		for _, data := range pushedData(res.LockingScript) {
+			if !filter.Matches(data) {
+				continue
+			}
+
+			matched = true
+			if filter.Flags == bloom.UpdateAll {
+				filter.Add(FilterOutpoint(tx.ID, resId))
+			}
+
+			break
+		}
+	}
+
+	if matched || tx.IsCoinbase() {
+		return matched
+	}
+
+	for _, req := range tx.Requests {
+		if filter.Matches(FilterOutpoint(req.ID, req.Out)) {
+			return true
+		}
+
+		for _, data := range pushedData(req.UnlockingScript) {
+			if filter.Matches(data) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// NewMerkleBlock matches the transactions of the block against the filter,
+// updating it as it goes.
+func NewMerkleBlock(block *Block, filter *bloom.Filter) *MerkleBlock {
+	merkleBlock := &MerkleBlock{Header: block.Header()}
+
+	matches := make([]bool, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		if tx.MatchesBloom(filter) {
+			matches[i] = true
+			merkleBlock.Transactions = append(merkleBlock.Transactions, tx)
+		}
+	}
+
+	if block.Version <= SerializedMerkleVersion {
+		merkleBlock.Tree = merkle.NewLegacyPartialTree(block.merkleLeaves(), matches)
+	} else {
+		merkleBlock.Tree = merkle.NewPartialTree(block.merkleLeaves(), matches)
+	}
+
+	return merkleBlock
+}
+
+// Verify checks the proof of work of the header and that every transaction
+// is a matched leaf of the partial tree, and returns their indexes in the
+// block. Like TxProof.Verify, it says nothing about the block being part of
+// the chain.
+func (merkleBlock *MerkleBlock) Verify() ([]int, error) {
+	var indexes []int
+
+	header := merkleBlock.Header
+	if !header.Validate() {
+		return nil, core.ErrInvalidBlock
+	}
+
+	root, matched, matchedIndexes, err := merkleBlock.Tree.Extract()
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(root, header.MerkleRoot) {
+		return nil, core.ErrInvalidProof
+	}
+
+	leaves := make(map[string]int)
+	for i, leaf := range matched {
+		leaves[string(leaf)] = matchedIndexes[i]
+	}
+
+	for _, tx := range merkleBlock.Transactions {
+		leaf := merkle.LeafHash(header.Block.merkleLeaf(tx), merkleBlock.Tree.Legacy)
+
+		index, ok := leaves[string(leaf)]
+		if !ok {
+			return nil, core.ErrInvalidProof
+		}
+
+		indexes = append(indexes, index)
+	}
+
+	return indexes, nil
+}
+
+// MerkleBlock layout: the header, the leaf count varint, the varint count of
+// hash bytes, the flag count varint and the flags packed in bytes, then the
+// varint count of transactions.
+func (merkleBlock *MerkleBlock) Serialize() []byte {
+	w := wire.NewWriter()
+	tree := merkleBlock.Tree
+
+	merkleBlock.Header.encode(w)
+
+	w.WriteVarInt(uint64(tree.Leaves))
+	w.WriteVarInt(uint64(len(tree.Hashes)))
+	for _, hash := range tree.Hashes {
+		w.WriteBytes(hash)
+	}
+
+	flags := make([]byte, (len(tree.Flags)+7)/8)
+	for i, flag := range tree.Flags {
+		if flag {
+			flags[i/8] |= 1 << (i % 8)
+		}
+	}
+
+	w.WriteVarInt(uint64(len(tree.Flags)))
+	w.WriteRaw(flags)
+
+	w.WriteVarInt(uint64(len(merkleBlock.Transactions)))
+	for _, tx := range merkleBlock.Transactions {
+		tx.encode(w, true)
+	}
+
+	return w.Bytes()
+}
+
+func DeserializeMerkleBlock(data []byte) (*MerkleBlock, error) {
+	var err error
+
+	r := wire.NewReader(data)
+	merkleBlock := &MerkleBlock{}
+
+	merkleBlock.Header, err = decodeHeader(r, false)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &merkle.PartialTree{
+		Leaves: r.ReadCount(),
+		Legacy: merkleBlock.Header.Block.Version <= SerializedMerkleVersion,
+	}
+
+	count := r.ReadCount()
+	for i := 0; i < count && r.Err() == nil; i++ {
+		tree.Hashes = append(tree.Hashes, r.ReadBytes())
+	}
+
+	flagCount := r.ReadCount()
+	flags := r.ReadRaw((flagCount + 7) / 8)
+	for i := 0; i < flagCount && r.Err() == nil; i++ {
+		tree.Flags = append(tree.Flags, flags[i/8]&(1<<(i%8)) != 0)
+	}
+
+	merkleBlock.Tree = tree
+
+	count = r.ReadCount()
+	for i := 0; i < count && r.Err() == nil; i++ {
+		tx, err := decodeTransaction(r, false)
+		if err != nil {
+			return nil, err
+		}
+
+		merkleBlock.Transactions = append(merkleBlock.Transactions, tx)
+	}
+
+	return merkleBlock, r.Finish()
+}
@@ -0,0 +1,83 @@
+package factory
+
+import (
+	"fmt"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+	"github.com/wilmacedo/willchain-go/storage"
+)
+
+// schemaVersionKey holds the layout version of the database. Databases
+// written before it existed are version 0.
+const schemaVersionKey = "sv"
+
+// migration upgrades the database from its position in migrations to the
+// next version and says what it did. Steps may run again if the process
+// stops before the new version is stored, so they have to be repeatable.
+// Indexes behind the tip are rebuilt by CheckConsistency, not here.
+type migration struct {
+	name string
+	run  func(chain *Blockchain) string
+}
+
+var migrations = []migration{
+	{"re-encode gob blocks in the wire format", func(chain *Blockchain) string {
+		return fmt.Sprintf("migrated %d blocks", chain.MigrateLegacyBlocks())
+	}},
+}
+
+// SchemaVersion is the layout this release writes.
+var SchemaVersion = uint32(len(migrations))
+
+func encodeSchemaVersion(version uint32) []byte {
+	w := wire.NewWriter()
+	w.WriteUint32(version)
+
+	return w.Bytes()
+}
+
+func putSchemaVersion(batch storage.Batch, version uint32) {
+	batch.Put([]byte(schemaVersionKey), encodeSchemaVersion(version))
+}
+
+func (chain *Blockchain) schemaVersion() (uint32, error) {
+	data, err := chain.Database.Get([]byte(schemaVersionKey))
+	if err == core.ErrKeyNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	r := wire.NewReader(data)
+	version := r.ReadUint32()
+
+	return version, r.Finish()
+}
+
+// Migrate upgrades the database one version at a time up to SchemaVersion,
+// adding what each step did to the upgrades of the chain. Databases written
+// by a newer release are refused.
+func (chain *Blockchain) Migrate() error {
+	version, err := chain.schemaVersion()
+	if err != nil {
+		return fmt.Errorf("%w: schema version: %v", core.ErrCorruptDatabase, err)
+	}
+
+	if version > SchemaVersion {
+		return fmt.Errorf("%w: database is version %d, this release reads up to %d", core.ErrNewerSchema, version, SchemaVersion)
+	}
+
+	for ; version < SchemaVersion; version++ {
+		step := migrations[version]
+		done := step.run(chain)
+
+		batch := chain.Database.NewBatch()
+		putSchemaVersion(batch, version+1)
+		core.Handle(chain.Database.Write(batch))
+
+		chain.Upgrades = append(chain.Upgrades, fmt.Sprintf("Upgraded the database to version %d, %s: %s", version+1, step.name, done))
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package factory
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/storage"
+)
+
+// legacyTestChain stores a chain of gob encoded blocks the way the first
+// releases did: no heights and no schema version, UTXO or filter index. Block
+// i has a coinbase paying 20 to key i, the last one also moves the coinbase of
+// block 0 to key 1. It returns the hashes from the genesis up.
+func legacyTestChain(t *testing.T, db storage.Database, count int) [][]byte {
+	var hashes [][]byte
+	var previous []byte
+
+	for i := 0; i < count; i++ {
+		coinbase := &legacyTransaction{
+			ID:       []byte(fmt.Sprintf("coinbase %d", i)),
+			Requests: []legacyRequest{{Out: -1, PubKey: []byte(fmt.Sprintf("data %d", i))}},
+			Results:  []legacyResult{{Value: 20, PubKeyHash: []byte(fmt.Sprintf("key %d", i))}},
+		}
+
+		block := legacyBlock{Transactions: []*legacyTransaction{coinbase}, PreviousHash: previous}
+
+		if i == count-1 {
+			block.Transactions = append(block.Transactions, &legacyTransaction{
+				ID:       []byte("transfer"),
+				Requests: []legacyRequest{{ID: []byte("coinbase 0"), Out: 0, Signature: []byte("signature"), PubKey: []byte("public key")}},
+				Results:  []legacyResult{{Value: 20, PubKeyHash: []byte("key 1")}},
+			})
+		}
+
+		hash := sha256.Sum256([]byte(fmt.Sprintf("legacy block %d", i)))
+		block.Hash = hash[:]
+
+		var encoded bytes.Buffer
+		if err := gob.NewEncoder(&encoded).Encode(block); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.Put(block.Hash, encoded.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+
+		hashes = append(hashes, block.Hash)
+		previous = block.Hash
+	}
+
+	if err := db.Put([]byte("lh"), previous); err != nil {
+		t.Fatal(err)
+	}
+
+	return hashes
+}
+
+func TestMigrate(t *testing.T) {
+	db := storage.NewMemory()
+	hashes := legacyTestChain(t, db, 3)
+
+	chain := &Blockchain{LastHash: hashes[2], Database: db}
+	if err := chain.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if version, err := chain.schemaVersion(); version != SchemaVersion || err != nil {
+		t.Errorf("schema version %d, %v", version, err)
+	}
+
+	if len(chain.Upgrades) != int(SchemaVersion) {
+		t.Errorf("upgrades %q", chain.Upgrades)
+	}
+
+	for height, hash := range hashes {
+		block, err := chain.Block(hash)
+		if err != nil {
+			t.Fatalf("block %d: %v", height, err)
+		}
+
+		if block.Version != LegacyVersion || block.Height != height || !bytes.Equal(block.Hash, hash) {
+			t.Errorf("block %d: version %d height %d hash %x", height, block.Version, block.Height, block.Hash)
+		}
+
+		if coinbase := block.Transactions[0]; !bytes.Equal(coinbase.ID, []byte(fmt.Sprintf("coinbase %d", height))) ||
+			!bytes.Equal(coinbase.Results[0].LockingScript, script.PayToPubKeyHash([]byte(fmt.Sprintf("key %d", height)))) {
+			t.Errorf("block %d: coinbase %v", height, coinbase)
+		}
+	}
+
+	tip, _ := chain.Block(hashes[2])
+	if unlocking := tip.Transactions[1].Requests[0].UnlockingScript; !bytes.Equal(unlocking, script.UnlockPubKeyHash([]byte("signature"), []byte("public key"))) {
+		t.Errorf("transfer unlocking script %x", unlocking)
+	}
+
+	// Migrating again finds nothing to do.
+	chain.Upgrades = nil
+	if err := chain.Migrate(); err != nil || len(chain.Upgrades) != 0 {
+		t.Errorf("second migration: %q, %v", chain.Upgrades, err)
+	}
+
+	if migrated := chain.MigrateLegacyBlocks(); migrated != 0 {
+		t.Errorf("migrated %d blocks again", migrated)
+	}
+}
+
+func TestMigrateSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version []byte
+		err     error
+	}{
+		{"newer", encodeSchemaVersion(SchemaVersion + 1), core.ErrNewerSchema},
+		{"truncated", encodeSchemaVersion(SchemaVersion)[:3], core.ErrCorruptDatabase},
+		{"current", encodeSchemaVersion(SchemaVersion), nil},
+	}
+
+	for _, test := range tests {
+		db := storage.NewMemory()
+		hashes := legacyTestChain(t, db, 1)
+		db.Put([]byte(schemaVersionKey), test.version)
+
+		chain := &Blockchain{LastHash: hashes[0], Database: db}
+		if err := chain.Migrate(); !errors.Is(err, test.err) {
+			t.Errorf("%s: %v, want %v", test.name, err, test.err)
+		}
+
+		// Refused databases are left alone.
+		if data, _ := db.Get(hashes[0]); test.err != nil && !isLegacyEncoding(data) {
+			t.Errorf("%s: blocks were changed", test.name)
+		}
+	}
+}
+
+func TestLoadLegacyBlockchain(t *testing.T) {
+	db := storage.NewMemory()
+	hashes := legacyTestChain(t, db, 3)
+
+	chain := LoadBlockchain(db)
+
+	if chain.Height() != 2 {
+		t.Errorf("height %d", chain.Height())
+	}
+
+	if tip, err := db.Get([]byte(utxoTipKey)); err != nil || !bytes.Equal(tip, hashes[2]) {
+		t.Errorf("UTXO tip %x, %v", tip, err)
+	}
+
+	for _, hash := range hashes {
+		if _, err := chain.FilterHeader(hash); err != nil {
+			t.Errorf("filter header of %x: %v", hash, err)
+		}
+	}
+
+	utxos := UTXOSet{chain}
+	balances := []struct {
+		key           string
+		unspent       int
+		confirmations int
+	}{
+		{"key 0", 0, 0},
+		{"key 1", 2, 2},
+		{"key 2", 1, 1},
+	}
+
+	for _, balance := range balances {
+		unspent := utxos.FindUnspentResults(script.PayToPubKeyHash([]byte(balance.key)))
+
+		if len(unspent) != balance.unspent || len(unspent) > 0 && unspent[0].Confirmations != balance.confirmations {
+			t.Errorf("%s: unspent %v", balance.key, unspent)
+		}
+	}
+
+	// Loading again has nothing to upgrade or repair.
+	if reloaded := LoadBlockchain(db); len(reloaded.Upgrades) != 0 {
+		t.Errorf("upgrades on reload: %q", reloaded.Upgrades)
+	}
+
+	// An index behind the tip is rebuilt on load.
+	db.Delete([]byte(utxoTipKey))
+	db.Delete(filterHeaderKey(hashes[2]))
+
+	if repaired := LoadBlockchain(db); len(repaired.Upgrades) != 2 {
+		t.Errorf("upgrades after losing the index tips: %q", repaired.Upgrades)
+	}
+}
@@ -0,0 +1,270 @@
+package factory
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+// PartialTransaction carries an unsigned transaction together with the
+// transactions its requests spend, so it can be signed on a machine without
+// access to the chain.
+type PartialTransaction struct {
+	Transaction Transaction
+	Inputs      []PartialInput
+}
+
+// PartialInput is what signing a request needs. PrevTransaction is carried
+// whole so that the signer can check PrevResult against the request: its
+// hash must be the ID the request spends.
+type PartialInput struct {
+	PrevTransaction Transaction
+	PrevResult      TXResult
+	RedeemScript    []byte
+	Signatures      map[string][]byte
+}
+
+// NewPartialTransaction looks up the results spent by tx. Pay to script hash
+// results take their redeem script from the scripts kept in wallets.
+func (chain *Blockchain) NewPartialTransaction(tx *Transaction, wallets *wallet.Wallets) (*PartialTransaction, error) {
+	ptx := &PartialTransaction{Transaction: *tx}
+	utxos := UTXOSet{chain}
+
+	for _, req := range tx.Requests {
+		unspent, ok := utxos.FindResult(req.ID, req.Out)
+		if !ok {
+			return nil, fmt.Errorf("%w: %x:%d", core.ErrMissingInput, req.ID, req.Out)
+		}
+
+		prevTx, err := chain.FindTransaction(req.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		input := PartialInput{
+			PrevTransaction: *prevTx,
+			PrevResult:      unspent.Result,
+			Signatures:      make(map[string][]byte),
+		}
+
+		if scriptHash, ok := script.ExtractScriptHash(unspent.Result.LockingScript); ok {
+			input.RedeemScript, ok = wallets.FindScriptByHash(scriptHash)
+			if !ok {
+				return nil, fmt.Errorf("%w: %x:%d", core.ErrUnknownRedeemScript, req.ID, req.Out)
+			}
+		}
+
+		ptx.Inputs = append(ptx.Inputs, input)
+	}
+
+	return ptx, nil
+}
+
+// checkInputs makes sure every input carries the transaction its request
+// spends and that PrevResult is the result spent, so that a file can't lie
+// about the value or the script being signed for.
+func (ptx *PartialTransaction) checkInputs() error {
+	if len(ptx.Inputs) != len(ptx.Transaction.Requests) {
+		return core.ErrPartialMismatch
+	}
+
+	for reqId, input := range ptx.Inputs {
+		req := ptx.Transaction.Requests[reqId]
+		prevTx := input.PrevTransaction
+
+		if !bytes.Equal(prevTx.ID, req.ID) || !bytes.Equal(prevTx.CalculateHash(), req.ID) ||
+			req.Out < 0 || req.Out >= len(prevTx.Results) ||
+			prevTx.Results[req.Out].Value != input.PrevResult.Value ||
+			!bytes.Equal(prevTx.Results[req.Out].LockingScript, input.PrevResult.LockingScript) {
+			return fmt.Errorf("%w: request %d", core.ErrPrevTransactionMismatch, reqId)
+		}
+	}
+
+	return nil
+}
+
+// Fee is what the inputs carry beyond the outputs, checked against the
+// previous transactions when the partial transaction was decoded.
+func (ptx *PartialTransaction) Fee() int {
+	fee := 0
+
+	for _, input := range ptx.Inputs {
+		fee += input.PrevResult.Value
+	}
+
+	for _, res := range ptx.Transaction.Results {
+		fee -= res.Value
+	}
+
+	return fee
+}
+
+func (input PartialInput) subscript() []byte {
+	if input.RedeemScript != nil {
+		return input.RedeemScript
+	}
+
+	return input.PrevResult.LockingScript
+}
+
+func (input PartialInput) canSign(pubKey []byte) bool {
+	if input.RedeemScript == nil {
+		return input.PrevResult.IsLockWithKey(wallet.PublicKeyHash(pubKey))
+	}
+
+	_, pubKeys, ok := script.ExtractMultisig(input.RedeemScript)
+	if !ok {
+		return false
+	}
+
+	for _, key := range pubKeys {
+		if bytes.Equal(key, pubKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sign adds a signature from w to every input it is able to unlock and
+// returns how many inputs it signed. Cosigners of a multisig input each add
+// their own signature in turn.
+func (ptx *PartialTransaction) Sign(w wallet.Wallet, hashType SigHashType) (int, error) {
+	signed := 0
+	key := hex.EncodeToString(w.PublicKey)
+
+	if err := ptx.checkInputs(); err != nil {
+		return signed, err
+	}
+
+	for reqId, input := range ptx.Inputs {
+		if !input.canSign(w.PublicKey) {
+			continue
+		}
+
+		signature, err := ptx.Transaction.RequestSignature(reqId, input.subscript(), hashType, w.Signer())
+		if err != nil {
+			return signed, err
+		}
+
+		input.Signatures[key] = signature
+		signed++
+	}
+
+	return signed, nil
+}
+
+func (ptx *PartialTransaction) Combine(other *PartialTransaction) error {
+	if !bytes.Equal(ptx.Transaction.Serialize(), other.Transaction.Serialize()) || len(ptx.Inputs) != len(other.Inputs) {
+		return core.ErrPartialMismatch
+	}
+
+	for reqId, input := range other.Inputs {
+		for key, signature := range input.Signatures {
+			ptx.Inputs[reqId].Signatures[key] = signature
+		}
+	}
+
+	return nil
+}
+
+// Finalize moves valid signatures of every input into the transaction and
+// returns it ready to be broadcast.
+func (ptx *PartialTransaction) Finalize() (*Transaction, error) {
+	txCopy := ptx.Transaction
+	txCopy.Requests = make([]TXRequest, len(ptx.Transaction.Requests))
+	copy(txCopy.Requests, ptx.Transaction.Requests)
+
+	for reqId, input := range ptx.Inputs {
+		if input.RedeemScript != nil {
+			txCopy.Requests[reqId].UnlockingScript = input.unlockMultisig(&txCopy, reqId)
+		} else {
+			txCopy.Requests[reqId].UnlockingScript = input.unlockPubKeyHash(&txCopy, reqId)
+		}
+
+		if txCopy.Requests[reqId].UnlockingScript == nil || !txCopy.VerifyRequest(reqId, input.PrevResult) {
+			return nil, fmt.Errorf("%w: request %d", core.ErrMissingSignature, reqId)
+		}
+	}
+
+	txCopy.ID = txCopy.CalculateHash()
+
+	return &txCopy, nil
+}
+
+func (input PartialInput) unlockPubKeyHash(tx *Transaction, reqId int) []byte {
+	for key, signature := range input.Signatures {
+		pubKey, err := hex.DecodeString(key)
+		if err != nil || !input.canSign(pubKey) {
+			continue
+		}
+
+		if tx.CheckSignature(reqId, input.subscript(), pubKey, signature) {
+			return script.UnlockPubKeyHash(signature, pubKey)
+		}
+	}
+
+	return nil
+}
+
+// unlockMultisig picks the first m valid signatures, in the order of the
+// public keys of the redeem script.
+func (input PartialInput) unlockMultisig(tx *Transaction, reqId int) []byte {
+	var signatures [][]byte
+
+	m, pubKeys, ok := script.ExtractMultisig(input.RedeemScript)
+	if !ok {
+		return nil
+	}
+
+	for _, pubKey := range pubKeys {
+		signature, ok := input.Signatures[hex.EncodeToString(pubKey)]
+		if !ok || !tx.CheckSignature(reqId, input.RedeemScript, pubKey, signature) {
+			continue
+		}
+
+		signatures = append(signatures, signature)
+		if len(signatures) == m {
+			return script.UnlockMultisig(signatures, input.RedeemScript)
+		}
+	}
+
+	return nil
+}
+
+func (ptx *PartialTransaction) Serialize() []byte {
+	var result bytes.Buffer
+	encoder := gob.NewEncoder(&result)
+
+	err := encoder.Encode(ptx)
+	core.Handle(err)
+
+	return result.Bytes()
+}
+
+func DeserializePartialTransaction(data []byte) (*PartialTransaction, error) {
+	var ptx PartialTransaction
+	decoder := gob.NewDecoder(bytes.NewBuffer(data))
+
+	err := decoder.Decode(&ptx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ptx.checkInputs(); err != nil {
+		return nil, err
+	}
+
+	for reqId := range ptx.Inputs {
+		if ptx.Inputs[reqId].Signatures == nil {
+			ptx.Inputs[reqId].Signatures = make(map[string][]byte)
+		}
+	}
+
+	return &ptx, nil
+}
@@ -0,0 +1,81 @@
+package factory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+// partialTestTransaction spends the genesis coinbase of validationTestChain,
+// paying 15 and leaving a fee of 5.
+func partialTestTransaction(t *testing.T) (*PartialTransaction, *wallet.Wallet) {
+	chain, w, genesis := validationTestChain(t)
+
+	wallets := &wallet.Wallets{Wallets: map[string]*wallet.Wallet{string(w.Address()): w}}
+	tx := NewRawTransaction([]Outpoint{{TxID: genesis.ID, Index: 0}}, []Payment{{Address: string(w.Address()), Amount: 15}}, 0, SequenceFinal)
+
+	ptx, err := chain.NewPartialTransaction(tx, wallets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ptx, w
+}
+
+func TestPartialTransaction(t *testing.T) {
+	ptx, w := partialTestTransaction(t)
+
+	if fee := ptx.Fee(); fee != 5 {
+		t.Errorf("fee %d", fee)
+	}
+
+	decoded, err := DeserializePartialTransaction(ptx.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signed, err := decoded.Sign(*w, SigHashAll); signed != 1 || err != nil {
+		t.Fatalf("signed %d, %v", signed, err)
+	}
+
+	if _, err := decoded.Finalize(); err != nil {
+		t.Error(err)
+	}
+}
+
+// A file lying about what its inputs spend is refused before anything is
+// signed, whether it is decoded or signed directly.
+func TestPartialTransactionTampered(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(input *PartialInput)
+	}{
+		{"higher value", func(input *PartialInput) { input.PrevResult.Value = 1000 }},
+		{"other locking script", func(input *PartialInput) { input.PrevResult.LockingScript = []byte("other script") }},
+		{"previous transaction changed", func(input *PartialInput) {
+			input.PrevTransaction.Results = []TXResult{{Value: 1000, LockingScript: input.PrevResult.LockingScript}}
+			input.PrevResult.Value = 1000
+		}},
+		{"previous transaction rehashed", func(input *PartialInput) {
+			input.PrevTransaction.Results = []TXResult{{Value: 1000, LockingScript: input.PrevResult.LockingScript}}
+			input.PrevTransaction.ID = input.PrevTransaction.CalculateHash()
+			input.PrevResult.Value = 1000
+		}},
+		{"no previous transaction", func(input *PartialInput) { input.PrevTransaction = Transaction{} }},
+	}
+
+	for _, test := range tests {
+		ptx, w := partialTestTransaction(t)
+		test.change(&ptx.Inputs[0])
+
+		if _, err := DeserializePartialTransaction(ptx.Serialize()); !errors.Is(err, core.ErrPrevTransactionMismatch) {
+			t.Errorf("%s: decoded, %v", test.name, err)
+		}
+
+		if signed, err := ptx.Sign(*w, SigHashAll); signed != 0 || !errors.Is(err, core.ErrPrevTransactionMismatch) {
+			t.Errorf("%s: signed %d, %v", test.name, signed, err)
+		}
+	}
+}
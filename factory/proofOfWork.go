@@ -1,11 +1,8 @@
 package factory
 
 import (
-	"bytes"
 	"crypto/sha256"
-	"encoding/binary"
 	"fmt"
-	"log"
 	"math"
 	"math/big"
 )
@@ -13,8 +10,10 @@ import (
 const Difficulty = 18
 
 type ProofOfWork struct {
-	Block  *Block
-	Target *big.Int
+	Block       *Block
+	Target      *big.Int
+	root        []byte
+	witnessRoot []byte
 }
 
 func NewProof(block *Block) *ProofOfWork {
@@ -30,14 +29,12 @@ func NewProof(block *Block) *ProofOfWork {
 }
 
 func (pow *ProofOfWork) InitData(nonce int) []byte {
-	data := bytes.Join([][]byte{
-		pow.Block.PreviousHash,
-		pow.Block.HashTransactions(),
-		ToHex(int64(nonce)),
-		ToHex(int64(Difficulty)),
-	}, []byte{})
-
-	return data
+	if pow.root == nil {
+		pow.root = pow.Block.HashTransactions()
+		pow.witnessRoot = pow.Block.HashWitnesses()
+	}
+
+	return pow.Block.header(pow.root, pow.witnessRoot, nonce)
 }
 
 func (pow *ProofOfWork) Run() (int, []byte) {
@@ -64,9 +61,18 @@ func (pow *ProofOfWork) Run() (int, []byte) {
 	return nonce, hash[:]
 }
 
+// Validate checks the proof of work of the block. The gob encoding legacy
+// blocks were mined over can't be rebuilt, so only the hash they carry is
+// checked against the target. They are read from the local database alone.
 func (pow *ProofOfWork) Validate() bool {
 	var initHash big.Int
 
+	if pow.Block.Version == LegacyVersion {
+		initHash.SetBytes(pow.Block.Hash)
+
+		return len(pow.Block.Hash) == sha256.Size && initHash.Cmp(pow.Target) == -1
+	}
+
 	data := pow.InitData(pow.Block.Nonce)
 
 	hash := sha256.Sum256(data)
@@ -74,13 +80,3 @@ func (pow *ProofOfWork) Validate() bool {
 
 	return initHash.Cmp(pow.Target) == -1
 }
-
-func ToHex(num int64) []byte {
-	buff := new(bytes.Buffer)
-	err := binary.Write(buff, binary.BigEndian, num)
-	if err != nil {
-		log.Panic(err)
-	}
-
-	return buff.Bytes()
-}
@@ -0,0 +1,449 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	MaxPubKeysPerMultisig = 20
+	lockTimeNumberSize    = 5
+)
+
+// Checker gives the interpreter access to the transaction being validated.
+type Checker interface {
+	CheckSig(signature, pubKey, subscript []byte) (bool, error)
+	CheckLockTime(lockTime int64) bool
+}
+
+type engine struct {
+	stack   [][]byte
+	checker Checker
+	ops     int
+}
+
+// Execute runs the unlocking script of a request followed by the locking
+// script of the result it spends. A nil error means the spend is authorized.
+// For pay to script hash results the last item pushed by the unlocking
+// script is then run as the redeem script over the remaining items.
+func Execute(unlocking, locking []byte, checker Checker) error {
+	if !IsPushOnly(unlocking) {
+		return core.ErrNotPushOnly
+	}
+
+	vm := &engine{checker: checker}
+
+	if err := vm.run(unlocking); err != nil {
+		return err
+	}
+
+	unlocked := make([][]byte, len(vm.stack))
+	copy(unlocked, vm.stack)
+
+	if err := vm.run(locking); err != nil {
+		return err
+	}
+
+	if !vm.succeeded() {
+		return core.ErrScriptFailed
+	}
+
+	if !IsPayToScriptHash(locking) {
+		return nil
+	}
+
+	redeemScript := unlocked[len(unlocked)-1]
+	redeem := &engine{
+		stack:   unlocked[:len(unlocked)-1],
+		checker: checker,
+	}
+
+	if err := redeem.run(redeemScript); err != nil {
+		return err
+	}
+
+	if !redeem.succeeded() {
+		return core.ErrScriptFailed
+	}
+
+	return nil
+}
+
+func Hash160(data []byte) []byte {
+	hash := sha256.Sum256(data)
+
+	hasher := ripemd160.New()
+	_, err := hasher.Write(hash[:])
+	core.Handle(err)
+
+	return hasher.Sum(nil)
+}
+
+func (vm *engine) run(script []byte) error {
+	var conditions []bool
+
+	instructions, err := Parse(script)
+	if err != nil {
+		return err
+	}
+
+	vm.ops = 0
+
+	for _, ins := range instructions {
+		executing := true
+		for _, condition := range conditions {
+			executing = executing && condition
+		}
+
+		if len(ins.Data) > MaxElementSize {
+			return core.ErrElementTooLarge
+		}
+
+		if !ins.IsPush() {
+			vm.ops++
+			if vm.ops > MaxOpsPerRun {
+				return core.ErrTooManyOperations
+			}
+		}
+
+		switch ins.Opcode {
+		case OP_IF, OP_NOTIF:
+			value := false
+
+			if executing {
+				top, err := vm.pop()
+				if err != nil {
+					return err
+				}
+
+				value = castToBool(top)
+				if ins.Opcode == OP_NOTIF {
+					value = !value
+				}
+			}
+
+			conditions = append(conditions, value)
+			continue
+
+		case OP_ELSE:
+			if len(conditions) == 0 {
+				return core.ErrUnbalancedConditional
+			}
+
+			conditions[len(conditions)-1] = !conditions[len(conditions)-1]
+			continue
+
+		case OP_ENDIF:
+			if len(conditions) == 0 {
+				return core.ErrUnbalancedConditional
+			}
+
+			conditions = conditions[:len(conditions)-1]
+			continue
+		}
+
+		if !executing {
+			continue
+		}
+
+		if ins.IsPush() {
+			vm.push(pushValue(ins))
+			continue
+		}
+
+		if err := vm.execute(ins.Opcode, script); err != nil {
+			return err
+		}
+	}
+
+	if len(conditions) != 0 {
+		return core.ErrUnbalancedConditional
+	}
+
+	return nil
+}
+
+func (vm *engine) execute(opcode byte, script []byte) error {
+	switch opcode {
+	case OP_NOP:
+		return nil
+
+	case OP_VERIFY:
+		top, err := vm.pop()
+		if err != nil {
+			return err
+		}
+
+		if !castToBool(top) {
+			return core.ErrScriptFailed
+		}
+
+	case OP_RETURN:
+		return core.ErrScriptFailed
+
+	case OP_DROP:
+		_, err := vm.pop()
+		return err
+
+	case OP_DUP:
+		top, err := vm.peek(0)
+		if err != nil {
+			return err
+		}
+
+		vm.push(top)
+
+	case OP_SWAP:
+		a, err := vm.pop()
+		if err != nil {
+			return err
+		}
+
+		b, err := vm.pop()
+		if err != nil {
+			return err
+		}
+
+		vm.push(a)
+		vm.push(b)
+
+	case OP_SIZE:
+		top, err := vm.peek(0)
+		if err != nil {
+			return err
+		}
+
+		vm.push(EncodeNumber(int64(len(top))))
+
+	case OP_EQUAL, OP_EQUALVERIFY:
+		a, err := vm.pop()
+		if err != nil {
+			return err
+		}
+
+		b, err := vm.pop()
+		if err != nil {
+			return err
+		}
+
+		equal := bytes.Equal(a, b)
+		if opcode == OP_EQUALVERIFY {
+			if !equal {
+				return core.ErrScriptFailed
+			}
+
+			return nil
+		}
+
+		vm.push(boolToStack(equal))
+
+	case OP_SHA256:
+		top, err := vm.pop()
+		if err != nil {
+			return err
+		}
+
+		hash := sha256.Sum256(top)
+		vm.push(hash[:])
+
+	case OP_HASH160:
+		top, err := vm.pop()
+		if err != nil {
+			return err
+		}
+
+		vm.push(Hash160(top))
+
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		pubKey, err := vm.pop()
+		if err != nil {
+			return err
+		}
+
+		signature, err := vm.pop()
+		if err != nil {
+			return err
+		}
+
+		valid, err := vm.checkSig(signature, pubKey, script)
+		if err != nil {
+			return err
+		}
+
+		if opcode == OP_CHECKSIGVERIFY {
+			if !valid {
+				return core.ErrScriptFailed
+			}
+
+			return nil
+		}
+
+		vm.push(boolToStack(valid))
+
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		valid, err := vm.checkMultisig(script)
+		if err != nil {
+			return err
+		}
+
+		if opcode == OP_CHECKMULTISIGVERIFY {
+			if !valid {
+				return core.ErrScriptFailed
+			}
+
+			return nil
+		}
+
+		vm.push(boolToStack(valid))
+
+	case OP_CHECKLOCKTIMEVERIFY:
+		top, err := vm.peek(0)
+		if err != nil {
+			return err
+		}
+
+		lockTime, err := DecodeNumber(top, lockTimeNumberSize)
+		if err != nil {
+			return err
+		}
+
+		if lockTime < 0 || !vm.checker.CheckLockTime(lockTime) {
+			return core.ErrLockTimeNotReached
+		}
+
+	default:
+		return core.ErrUnknownOpcode
+	}
+
+	return nil
+}
+
+// checkMultisig pops <sig...> m <pubkey...> n. Signatures have to follow the
+// same order as the public keys they belong to.
+func (vm *engine) checkMultisig(script []byte) (bool, error) {
+	n, err := vm.popNumber()
+	if err != nil {
+		return false, err
+	}
+
+	if n < 0 || n > MaxPubKeysPerMultisig {
+		return false, core.ErrInvalidMultisig
+	}
+
+	vm.ops += int(n)
+	if vm.ops > MaxOpsPerRun {
+		return false, core.ErrTooManyOperations
+	}
+
+	pubKeys := make([][]byte, n)
+	for i := int(n) - 1; i >= 0; i-- {
+		pubKeys[i], err = vm.pop()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	m, err := vm.popNumber()
+	if err != nil {
+		return false, err
+	}
+
+	if m < 0 || m > n {
+		return false, core.ErrInvalidMultisig
+	}
+
+	signatures := make([][]byte, m)
+	for i := int(m) - 1; i >= 0; i-- {
+		signatures[i], err = vm.pop()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	key := 0
+	for _, signature := range signatures {
+		matched := false
+
+		for len(pubKeys)-key >= 1 && !matched {
+			matched, err = vm.checkSig(signature, pubKeys[key], script)
+			if err != nil {
+				return false, err
+			}
+
+			key++
+		}
+
+		if !matched {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// checkSig treats an empty signature as a failed check, any other one has to
+// be canonically encoded.
+func (vm *engine) checkSig(signature, pubKey, script []byte) (bool, error) {
+	if len(signature) == 0 {
+		return false, nil
+	}
+
+	return vm.checker.CheckSig(signature, pubKey, script)
+}
+
+func (vm *engine) succeeded() bool {
+	return len(vm.stack) > 0 && castToBool(vm.stack[len(vm.stack)-1])
+}
+
+func (vm *engine) push(data []byte) {
+	vm.stack = append(vm.stack, data)
+}
+
+func (vm *engine) pop() ([]byte, error) {
+	if len(vm.stack) == 0 {
+		return nil, core.ErrStackUnderflow
+	}
+
+	top := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+
+	return top, nil
+}
+
+func (vm *engine) peek(depth int) ([]byte, error) {
+	if len(vm.stack) <= depth {
+		return nil, core.ErrStackUnderflow
+	}
+
+	return vm.stack[len(vm.stack)-1-depth], nil
+}
+
+func (vm *engine) popNumber() (int64, error) {
+	top, err := vm.pop()
+	if err != nil {
+		return 0, err
+	}
+
+	return DecodeNumber(top, 4)
+}
+
+func castToBool(data []byte) bool {
+	for i, b := range data {
+		if b != 0 {
+			return !(i == len(data)-1 && b == 0x80)
+		}
+	}
+
+	return false
+}
+
+func boolToStack(value bool) []byte {
+	if value {
+		return []byte{1}
+	}
+
+	return []byte{}
+}
@@ -0,0 +1,100 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// errAny stands for a failure without checking which.
+var errAny = errors.New("any error")
+
+// testChecker accepts a signature made of "sig" and the public key, and lock
+// times up to its own.
+type testChecker struct {
+	lockTime int64
+}
+
+func sign(pubKey []byte) []byte {
+	return append([]byte("sig"), pubKey...)
+}
+
+func (checker testChecker) CheckSig(signature, pubKey, subscript []byte) (bool, error) {
+	return bytes.Equal(signature, sign(pubKey)), nil
+}
+
+func (checker testChecker) CheckLockTime(lockTime int64) bool {
+	return lockTime <= checker.lockTime
+}
+
+func TestExecute(t *testing.T) {
+	keyA, keyB, keyC := []byte("public key A"), []byte("public key B"), []byte("public key C")
+
+	multisig, err := MultisigScript(2, [][]byte{keyA, keyB, keyC})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := bytes.Repeat([]byte{7}, SecretSize)
+	secretHash := sha256.Sum256(secret)
+	swap := AtomicSwapScript(AtomicSwap{
+		SecretHash:    secretHash[:],
+		RecipientHash: Hash160(keyA),
+		RefundHash:    Hash160(keyB),
+		LockTime:      100,
+	})
+
+	timeLocked := NewBuilder().AddInt(100).AddOp(OP_CHECKLOCKTIMEVERIFY).AddOp(OP_DROP).AddOp(OP_TRUE).Script()
+
+	tests := []struct {
+		name      string
+		unlocking []byte
+		locking   []byte
+		lockTime  int64
+		err       error
+	}{
+		{"p2pkh", UnlockPubKeyHash(sign(keyA), keyA), PayToPubKeyHash(Hash160(keyA)), 0, nil},
+		{"p2pkh wrong key", UnlockPubKeyHash(sign(keyB), keyB), PayToPubKeyHash(Hash160(keyA)), 0, errAny},
+		{"p2pkh wrong signature", UnlockPubKeyHash(sign(keyB), keyA), PayToPubKeyHash(Hash160(keyA)), 0, core.ErrScriptFailed},
+		{"p2pkh not push only", NewBuilder().AddOp(OP_DUP).Script(), PayToPubKeyHash(Hash160(keyA)), 0, core.ErrNotPushOnly},
+		{"p2sh multisig", UnlockMultisig([][]byte{sign(keyA), sign(keyC)}, multisig), PayToScriptHash(Hash160(multisig)), 0, nil},
+		{"p2sh multisig out of order", UnlockMultisig([][]byte{sign(keyC), sign(keyA)}, multisig), PayToScriptHash(Hash160(multisig)), 0, core.ErrScriptFailed},
+		{"p2sh multisig one signature", UnlockMultisig([][]byte{nil, sign(keyB)}, multisig), PayToScriptHash(Hash160(multisig)), 0, core.ErrScriptFailed},
+		{"p2sh wrong redeem script", UnlockMultisig([][]byte{sign(keyA), sign(keyB)}, multisig), PayToScriptHash(Hash160(swap)), 0, core.ErrScriptFailed},
+		{"cltv reached", nil, timeLocked, 100, nil},
+		{"cltv not reached", nil, timeLocked, 99, core.ErrLockTimeNotReached},
+		{"swap redeem", UnlockAtomicSwapRedeem(sign(keyA), keyA, secret, swap), PayToScriptHash(Hash160(swap)), 0, nil},
+		{"swap redeem wrong secret", UnlockAtomicSwapRedeem(sign(keyA), keyA, bytes.Repeat([]byte{8}, SecretSize), swap), PayToScriptHash(Hash160(swap)), 0, errAny},
+		{"swap refund after lock time", UnlockAtomicSwapRefund(sign(keyB), keyB, swap), PayToScriptHash(Hash160(swap)), 100, nil},
+		{"swap refund before lock time", UnlockAtomicSwapRefund(sign(keyB), keyB, swap), PayToScriptHash(Hash160(swap)), 99, core.ErrLockTimeNotReached},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Execute(test.unlocking, test.locking, testChecker{test.lockTime})
+
+			switch {
+			case test.err == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.err != nil && err == nil:
+				t.Fatal("spend was authorized")
+			case test.err != nil && test.err != errAny && !errors.Is(err, test.err):
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestNumberEncoding(t *testing.T) {
+	tests := []int64{0, 1, -1, 16, 127, 128, -128, 255, 256, 32767, 32768, 1 << 31, -(1 << 31), 1<<39 - 1}
+
+	for _, number := range tests {
+		decoded, err := DecodeNumber(EncodeNumber(number), lockTimeNumberSize)
+		if err != nil || decoded != number {
+			t.Errorf("%d: decoded %d, %v", number, decoded, err)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package script
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+const (
+	MaxScriptSize  = 10000
+	MaxElementSize = 520
+	MaxOpsPerRun   = 201
+
+	MaxDataCarrierSize = 80
+)
+
+type Instruction struct {
+	Opcode byte
+	Data   []byte
+}
+
+type Builder struct {
+	script []byte
+}
+
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+func (b *Builder) AddOp(opcode byte) *Builder {
+	b.script = append(b.script, opcode)
+
+	return b
+}
+
+// AddData pushes data with the smallest push operation able to hold it.
+func (b *Builder) AddData(data []byte) *Builder {
+	size := len(data)
+
+	switch {
+	case size < OP_PUSHDATA1:
+		b.script = append(b.script, byte(size))
+	case size <= 0xff:
+		b.script = append(b.script, OP_PUSHDATA1, byte(size))
+	default:
+		length := make([]byte, 2)
+		binary.LittleEndian.PutUint16(length, uint16(size))
+
+		b.script = append(b.script, OP_PUSHDATA2)
+		b.script = append(b.script, length...)
+	}
+
+	b.script = append(b.script, data...)
+
+	return b
+}
+
+// AddInt pushes a number, using OP_0 and OP_1 to OP_16 when possible.
+func (b *Builder) AddInt(number int64) *Builder {
+	switch {
+	case number == 0:
+		return b.AddOp(OP_0)
+	case number == -1:
+		return b.AddOp(OP_1NEGATE)
+	case number >= 1 && number <= 16:
+		return b.AddOp(byte(OP_1 - 1 + number))
+	}
+
+	return b.AddData(EncodeNumber(number))
+}
+
+func (b *Builder) Script() []byte {
+	script := make([]byte, len(b.script))
+	copy(script, b.script)
+
+	return script
+}
+
+func Parse(script []byte) ([]Instruction, error) {
+	var instructions []Instruction
+
+	if len(script) > MaxScriptSize {
+		return nil, core.ErrScriptTooLarge
+	}
+
+	for pc := 0; pc < len(script); {
+		opcode := script[pc]
+		pc++
+
+		size := 0
+
+		switch {
+		case opcode > OP_0 && opcode < OP_PUSHDATA1:
+			size = int(opcode)
+		case opcode == OP_PUSHDATA1:
+			if pc+1 > len(script) {
+				return nil, core.ErrMalformedScript
+			}
+
+			size = int(script[pc])
+			pc++
+		case opcode == OP_PUSHDATA2:
+			if pc+2 > len(script) {
+				return nil, core.ErrMalformedScript
+			}
+
+			size = int(binary.LittleEndian.Uint16(script[pc : pc+2]))
+			pc += 2
+		}
+
+		if pc+size > len(script) {
+			return nil, core.ErrMalformedScript
+		}
+
+		instruction := Instruction{Opcode: opcode}
+		if opcode > OP_0 && opcode <= OP_PUSHDATA2 {
+			instruction.Data = script[pc : pc+size]
+		}
+		pc += size
+
+		instructions = append(instructions, instruction)
+	}
+
+	return instructions, nil
+}
+
+func (ins Instruction) IsPush() bool {
+	return ins.Opcode <= OP_PUSHDATA2 || ins.Opcode == OP_1NEGATE || (ins.Opcode >= OP_1 && ins.Opcode <= OP_16)
+}
+
+func IsPushOnly(script []byte) bool {
+	instructions, err := Parse(script)
+	if err != nil {
+		return false
+	}
+
+	for _, ins := range instructions {
+		if !ins.IsPush() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PushedData returns the items a push only script leaves on the stack.
+func PushedData(script []byte) ([][]byte, error) {
+	var items [][]byte
+
+	instructions, err := Parse(script)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ins := range instructions {
+		if !ins.IsPush() {
+			return nil, core.ErrNotPushOnly
+		}
+
+		items = append(items, pushValue(ins))
+	}
+
+	return items, nil
+}
+
+func Disassemble(script []byte) string {
+	var parts []string
+
+	instructions, err := Parse(script)
+	if err != nil {
+		return fmt.Sprintf("[invalid] %x", script)
+	}
+
+	for _, ins := range instructions {
+		switch {
+		case ins.Opcode > OP_0 && ins.Opcode <= OP_PUSHDATA2:
+			parts = append(parts, hex.EncodeToString(ins.Data))
+		case ins.Opcode >= OP_1 && ins.Opcode <= OP_16:
+			parts = append(parts, fmt.Sprintf("OP_%d", ins.Opcode-OP_1+1))
+		default:
+			name, ok := opcodeNames[ins.Opcode]
+			if !ok {
+				name = fmt.Sprintf("OP_UNKNOWN_%x", ins.Opcode)
+			}
+
+			parts = append(parts, name)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func pushValue(ins Instruction) []byte {
+	switch {
+	case ins.Opcode == OP_0:
+		return []byte{}
+	case ins.Opcode == OP_1NEGATE:
+		return EncodeNumber(-1)
+	case ins.Opcode >= OP_1 && ins.Opcode <= OP_16:
+		return EncodeNumber(int64(ins.Opcode - OP_1 + 1))
+	}
+
+	return ins.Data
+}
+
+// EncodeNumber uses the minimal little endian sign and magnitude encoding
+// numbers have on the stack.
+func EncodeNumber(number int64) []byte {
+	if number == 0 {
+		return []byte{}
+	}
+
+	negative := number < 0
+	if negative {
+		number = -number
+	}
+
+	var result []byte
+	for number > 0 {
+		result = append(result, byte(number&0xff))
+		number >>= 8
+	}
+
+	if result[len(result)-1]&0x80 != 0 {
+		extra := byte(0x00)
+		if negative {
+			extra = 0x80
+		}
+
+		result = append(result, extra)
+	} else if negative {
+		result[len(result)-1] |= 0x80
+	}
+
+	return result
+}
+
+func DecodeNumber(data []byte, maxSize int) (int64, error) {
+	if len(data) > maxSize {
+		return 0, core.ErrNumberOverflow
+	}
+
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	last := data[len(data)-1]
+	if last&0x7f == 0 && (len(data) == 1 || data[len(data)-2]&0x80 == 0) {
+		return 0, core.ErrNonMinimalNumber
+	}
+
+	var result int64
+	for i, b := range data {
+		result |= int64(b) << uint(8*i)
+	}
+
+	if last&0x80 != 0 {
+		result &= ^(int64(0x80) << uint(8*(len(data)-1)))
+
+		return -result, nil
+	}
+
+	return result, nil
+}
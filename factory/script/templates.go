@@ -0,0 +1,262 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+const pubKeyHashLength = 20
+
+// PayToPubKeyHash locks a result to the owner of a public key:
+// OP_DUP OP_HASH160 <hash> OP_EQUALVERIFY OP_CHECKSIG
+func PayToPubKeyHash(pubKeyHash []byte) []byte {
+	return NewBuilder().
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(pubKeyHash).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+func UnlockPubKeyHash(signature, pubKey []byte) []byte {
+	return NewBuilder().AddData(signature).AddData(pubKey).Script()
+}
+
+// ExtractPubKeyHash returns the key hash of a pay to public key hash script.
+func ExtractPubKeyHash(script []byte) ([]byte, bool) {
+	template := PayToPubKeyHash(make([]byte, pubKeyHashLength))
+
+	if len(script) != len(template) {
+		return nil, false
+	}
+
+	if !bytes.Equal(script[:3], template[:3]) || !bytes.Equal(script[23:], template[23:]) {
+		return nil, false
+	}
+
+	return script[3:23], true
+}
+
+// PayToScriptHash locks a result to a redeem script revealed when spending:
+// OP_HASH160 <hash> OP_EQUAL
+func PayToScriptHash(scriptHash []byte) []byte {
+	return NewBuilder().
+		AddOp(OP_HASH160).
+		AddData(scriptHash).
+		AddOp(OP_EQUAL).
+		Script()
+}
+
+func ExtractScriptHash(script []byte) ([]byte, bool) {
+	if !IsPayToScriptHash(script) {
+		return nil, false
+	}
+
+	return script[2:22], true
+}
+
+func IsPayToScriptHash(script []byte) bool {
+	return len(script) == 23 && script[0] == OP_HASH160 && script[1] == pubKeyHashLength && script[22] == OP_EQUAL
+}
+
+// MultisigScript is the redeem script of an m-of-n multisignature:
+// m <pubkey...> n OP_CHECKMULTISIG
+func MultisigScript(m int, pubKeys [][]byte) ([]byte, error) {
+	if m < 1 || m > len(pubKeys) || len(pubKeys) > MaxPubKeysPerMultisig {
+		return nil, core.ErrInvalidMultisig
+	}
+
+	builder := NewBuilder().AddInt(int64(m))
+	for _, pubKey := range pubKeys {
+		builder.AddData(pubKey)
+	}
+
+	return builder.AddInt(int64(len(pubKeys))).AddOp(OP_CHECKMULTISIG).Script(), nil
+}
+
+func ExtractMultisig(script []byte) (int, [][]byte, bool) {
+	var pubKeys [][]byte
+
+	instructions, err := Parse(script)
+	if err != nil || len(instructions) < 4 {
+		return 0, nil, false
+	}
+
+	last := len(instructions) - 1
+	if instructions[last].Opcode != OP_CHECKMULTISIG {
+		return 0, nil, false
+	}
+
+	m, okM := smallInt(instructions[0])
+	n, okN := smallInt(instructions[last-1])
+	if !okM || !okN || n != last-2 || m < 1 || m > n {
+		return 0, nil, false
+	}
+
+	for _, ins := range instructions[1 : last-1] {
+		if ins.Opcode == OP_0 || ins.Opcode > OP_PUSHDATA2 {
+			return 0, nil, false
+		}
+
+		pubKeys = append(pubKeys, ins.Data)
+	}
+
+	return m, pubKeys, true
+}
+
+// UnlockMultisig spends a pay to script hash multisig result. Signatures
+// must follow the order of their public keys in the redeem script.
+func UnlockMultisig(signatures [][]byte, redeemScript []byte) []byte {
+	builder := NewBuilder()
+
+	for _, signature := range signatures {
+		builder.AddData(signature)
+	}
+
+	return builder.AddData(redeemScript).Script()
+}
+
+func smallInt(ins Instruction) (int, bool) {
+	if ins.Opcode >= OP_1 && ins.Opcode <= OP_16 {
+		return int(ins.Opcode-OP_1) + 1, true
+	}
+
+	if ins.Opcode > OP_0 && ins.Opcode < OP_PUSHDATA1 {
+		number, err := DecodeNumber(ins.Data, 4)
+		return int(number), err == nil
+	}
+
+	return 0, false
+}
+
+const SecretSize = 32
+
+type AtomicSwap struct {
+	SecretHash    []byte
+	RecipientHash []byte
+	RefundHash    []byte
+	LockTime      int64
+}
+
+// AtomicSwapScript is the redeem script of a hash time locked contract. The
+// recipient spends it revealing the preimage of the secret hash, the refund
+// key once the lock time is reached:
+// OP_IF OP_SIZE 32 OP_EQUALVERIFY OP_SHA256 <secret hash> OP_EQUALVERIFY OP_DUP OP_HASH160 <recipient>
+// OP_ELSE <lock time> OP_CHECKLOCKTIMEVERIFY OP_DROP OP_DUP OP_HASH160 <refund>
+// OP_ENDIF OP_EQUALVERIFY OP_CHECKSIG
+func AtomicSwapScript(swap AtomicSwap) []byte {
+	return NewBuilder().
+		AddOp(OP_IF).
+		AddOp(OP_SIZE).
+		AddInt(SecretSize).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_SHA256).
+		AddData(swap.SecretHash).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(swap.RecipientHash).
+		AddOp(OP_ELSE).
+		AddInt(swap.LockTime).
+		AddOp(OP_CHECKLOCKTIMEVERIFY).
+		AddOp(OP_DROP).
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(swap.RefundHash).
+		AddOp(OP_ENDIF).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+func ExtractAtomicSwap(script []byte) (AtomicSwap, bool) {
+	instructions, err := Parse(script)
+	if err != nil || len(instructions) != 20 {
+		return AtomicSwap{}, false
+	}
+
+	lockTime, err := DecodeNumber(pushValue(instructions[11]), lockTimeNumberSize)
+	if err != nil || !instructions[11].IsPush() {
+		return AtomicSwap{}, false
+	}
+
+	swap := AtomicSwap{
+		SecretHash:    instructions[5].Data,
+		RecipientHash: instructions[9].Data,
+		RefundHash:    instructions[16].Data,
+		LockTime:      lockTime,
+	}
+
+	if len(swap.SecretHash) != sha256.Size || len(swap.RecipientHash) != pubKeyHashLength || len(swap.RefundHash) != pubKeyHashLength {
+		return AtomicSwap{}, false
+	}
+
+	if !bytes.Equal(script, AtomicSwapScript(swap)) {
+		return AtomicSwap{}, false
+	}
+
+	return swap, true
+}
+
+func UnlockAtomicSwapRedeem(signature, pubKey, secret, contract []byte) []byte {
+	return NewBuilder().
+		AddData(signature).
+		AddData(pubKey).
+		AddData(secret).
+		AddOp(OP_TRUE).
+		AddData(contract).
+		Script()
+}
+
+func UnlockAtomicSwapRefund(signature, pubKey, contract []byte) []byte {
+	return NewBuilder().
+		AddData(signature).
+		AddData(pubKey).
+		AddOp(OP_FALSE).
+		AddData(contract).
+		Script()
+}
+
+// ExtractAtomicSwapSecret returns the secret revealed by the unlocking script
+// of a contract redemption.
+func ExtractAtomicSwapSecret(unlocking []byte) ([]byte, bool) {
+	items, err := PushedData(unlocking)
+	if err != nil || len(items) != 5 || !castToBool(items[3]) {
+		return nil, false
+	}
+
+	if _, ok := ExtractAtomicSwap(items[4]); !ok {
+		return nil, false
+	}
+
+	return items[2], true
+}
+
+// NullData is an unspendable output carrying data: OP_RETURN <data>
+func NullData(data []byte) ([]byte, error) {
+	if len(data) > MaxDataCarrierSize {
+		return nil, core.ErrDataTooLarge
+	}
+
+	return NewBuilder().AddOp(OP_RETURN).AddData(data).Script(), nil
+}
+
+func IsUnspendable(script []byte) bool {
+	return len(script) > 0 && script[0] == OP_RETURN
+}
+
+func ExtractNullData(script []byte) ([]byte, bool) {
+	instructions, err := Parse(script)
+	if err != nil || len(instructions) != 2 || instructions[0].Opcode != OP_RETURN {
+		return nil, false
+	}
+
+	if !instructions[1].IsPush() || len(instructions[1].Data) > MaxDataCarrierSize {
+		return nil, false
+	}
+
+	return instructions[1].Data, true
+}
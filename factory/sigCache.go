@@ -0,0 +1,74 @@
+package factory
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+const DefaultSigCacheSize = 50000
+
+// SigCache remembers the signatures that verified, so a transaction checked
+// when it was accepted is not verified again when its block is connected.
+// Entries are keyed by the signature hash, the public key and the signature,
+// when it is full a random entry is evicted. It lives in memory with its
+// Blockchain and there is no mempool kept between commands, so the sharing
+// only happens within one process, as in sendrawtransaction.
+type SigCache struct {
+	mu         sync.RWMutex
+	entries    map[[sha256.Size]byte]struct{}
+	maxEntries int
+}
+
+func NewSigCache(maxEntries int) *SigCache {
+	return &SigCache{
+		entries:    make(map[[sha256.Size]byte]struct{}),
+		maxEntries: maxEntries,
+	}
+}
+
+func sigCacheKey(sigHash, pubKey, signature []byte) [sha256.Size]byte {
+	w := wire.NewWriter()
+	w.WriteBytes(sigHash)
+	w.WriteBytes(pubKey)
+	w.WriteBytes(signature)
+
+	return sha256.Sum256(w.Bytes())
+}
+
+// Exists is false on a nil cache.
+func (cache *SigCache) Exists(sigHash, pubKey, signature []byte) bool {
+	if cache == nil {
+		return false
+	}
+
+	key := sigCacheKey(sigHash, pubKey, signature)
+
+	cache.mu.RLock()
+	_, ok := cache.entries[key]
+	cache.mu.RUnlock()
+
+	return ok
+}
+
+func (cache *SigCache) Add(sigHash, pubKey, signature []byte) {
+	if cache == nil || cache.maxEntries <= 0 {
+		return
+	}
+
+	key := sigCacheKey(sigHash, pubKey, signature)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if len(cache.entries) >= cache.maxEntries {
+		// map iteration starts at a random entry
+		for evicted := range cache.entries {
+			delete(cache.entries, evicted)
+			break
+		}
+	}
+
+	cache.entries[key] = struct{}{}
+}
@@ -0,0 +1,155 @@
+package factory
+
+import (
+	"crypto/sha256"
+	"strings"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+// SigHashType is appended to every signature and selects which parts of the
+// transaction the signature commits to.
+type SigHashType byte
+
+const (
+	SigHashAll    SigHashType = 0x01
+	SigHashNone   SigHashType = 0x02
+	SigHashSingle SigHashType = 0x03
+
+	// SigHashAnyoneCanPay combined with another type only commits to the
+	// signed request, so others can add their own inputs.
+	SigHashAnyoneCanPay SigHashType = 0x80
+)
+
+var sigHashNames = map[SigHashType]string{
+	SigHashAll:    "ALL",
+	SigHashNone:   "NONE",
+	SigHashSingle: "SINGLE",
+}
+
+// ParseSigHashType reads names like ALL, NONE|ANYONECANPAY or SINGLE.
+func ParseSigHashType(name string) (SigHashType, error) {
+	var hashType SigHashType
+
+	parts := strings.Split(strings.ToUpper(name), "|")
+	if len(parts) == 2 && parts[1] == "ANYONECANPAY" {
+		hashType = SigHashAnyoneCanPay
+	} else if len(parts) != 1 {
+		return 0, core.ErrUnknownSigHashType
+	}
+
+	for base, baseName := range sigHashNames {
+		if parts[0] == baseName {
+			return hashType | base, nil
+		}
+	}
+
+	return 0, core.ErrUnknownSigHashType
+}
+
+func (hashType SigHashType) String() string {
+	name := sigHashNames[hashType.base()]
+
+	if hashType&SigHashAnyoneCanPay != 0 {
+		name += "|ANYONECANPAY"
+	}
+
+	return name
+}
+
+func (hashType SigHashType) base() SigHashType {
+	return hashType &^ SigHashAnyoneCanPay
+}
+
+func (hashType SigHashType) valid() bool {
+	_, ok := sigHashNames[hashType.base()]
+
+	return ok && hashType&^(SigHashAnyoneCanPay|0x03) == 0
+}
+
+// SignatureHash is the digest signed by the request at reqId. The subscript
+// is the script that checks the signature, usually the locking script of the
+// spent result. NONE leaves every result out and SINGLE keeps only the
+// result at the index of the request, both letting other requests change
+// their sequence.
+func (tx *Transaction) SignatureHash(reqId int, subscript []byte, hashType SigHashType) ([]byte, error) {
+	if !hashType.valid() {
+		return nil, core.ErrUnknownSigHashType
+	}
+
+	txCopy := tx.TrimmedCopy()
+	txCopy.ID = []byte{}
+	txCopy.Requests[reqId].UnlockingScript = subscript
+
+	switch hashType.base() {
+	case SigHashNone:
+		txCopy.Results = nil
+
+	case SigHashSingle:
+		if reqId >= len(txCopy.Results) {
+			return nil, core.ErrSigHashSingle
+		}
+
+		txCopy.Results = txCopy.Results[:reqId+1]
+		for i := 0; i < reqId; i++ {
+			txCopy.Results[i] = TXResult{Value: -1}
+		}
+	}
+
+	if hashType.base() != SigHashAll {
+		for i := range txCopy.Requests {
+			if i != reqId {
+				txCopy.Requests[i].Sequence = 0
+			}
+		}
+	}
+
+	if hashType&SigHashAnyoneCanPay != 0 {
+		txCopy.Requests = txCopy.Requests[reqId : reqId+1]
+	}
+
+	hash := sha256.Sum256(append(txCopy.Serialize(), byte(hashType)))
+
+	return hash[:], nil
+}
+
+// RequestSignature signs the request at reqId and appends hashType.
+func (tx *Transaction) RequestSignature(reqId int, subscript []byte, hashType SigHashType, signer wallet.Signer) ([]byte, error) {
+	hash, err := tx.SignatureHash(reqId, subscript, hashType)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(signer.Sign(hash), byte(hashType)), nil
+}
+
+// CheckSignature verifies a signature with its hash type appended.
+func (tx *Transaction) CheckSignature(reqId int, subscript, pubKey, signature []byte) bool {
+	return tx.checkSignature(reqId, subscript, pubKey, signature, nil)
+}
+
+func (tx *Transaction) checkSignature(reqId int, subscript, pubKey, signature []byte, cache *SigCache) bool {
+	if len(signature) < 2 {
+		return false
+	}
+
+	hashType := SigHashType(signature[len(signature)-1])
+
+	hash, err := tx.SignatureHash(reqId, subscript, hashType)
+	if err != nil {
+		return false
+	}
+
+	if cache.Exists(hash, pubKey, signature) {
+		return true
+	}
+
+	if !VerifyHash(pubKey, hash, signature[:len(signature)-1]) {
+		return false
+	}
+
+	cache.Add(hash, pubKey, signature)
+
+	return true
+}
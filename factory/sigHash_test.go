@@ -0,0 +1,129 @@
+package factory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+func sigHashTestTx() *Transaction {
+	tx := &Transaction{
+		Version: TransactionVersion,
+		Requests: []TXRequest{
+			{ID: []byte("previous transaction 1"), Out: 0, Sequence: 1},
+			{ID: []byte("previous transaction 2"), Out: 1, Sequence: 2},
+		},
+		Results: []TXResult{
+			{Value: 10, LockingScript: script.PayToPubKeyHash([]byte("recipient 1"))},
+			{Value: 20, LockingScript: script.PayToPubKeyHash([]byte("recipient 2"))},
+		},
+	}
+	tx.ID = tx.CalculateHash()
+
+	return tx
+}
+
+func TestSignatureHashTypes(t *testing.T) {
+	privateKey, pubKey, err := wallet.GenerateKey(wallet.DefaultAlgorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := wallet.NewSigner(wallet.DefaultAlgorithm, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscript := script.PayToPubKeyHash(wallet.PublicKeyHash(pubKey))
+
+	changes := []struct {
+		name   string
+		change func(tx *Transaction)
+	}{
+		{"signed result", func(tx *Transaction) { tx.Results[0].Value++ }},
+		{"other result", func(tx *Transaction) { tx.Results[1].Value++ }},
+		{"added result", func(tx *Transaction) { tx.Results = append(tx.Results, TXResult{Value: 1}) }},
+		{"other sequence", func(tx *Transaction) { tx.Requests[1].Sequence++ }},
+		{"added request", func(tx *Transaction) { tx.Requests = append(tx.Requests, TXRequest{ID: []byte("extra"), Out: 0}) }},
+		{"signed sequence", func(tx *Transaction) { tx.Requests[0].Sequence++ }},
+	}
+
+	// Whether the signature of request 0 still holds after each change.
+	tests := []struct {
+		hashType SigHashType
+		valid    []bool
+	}{
+		{SigHashAll, []bool{false, false, false, false, false, false}},
+		{SigHashNone, []bool{true, true, true, true, false, false}},
+		{SigHashSingle, []bool{false, true, true, true, false, false}},
+		{SigHashAll | SigHashAnyoneCanPay, []bool{false, false, false, true, true, false}},
+		{SigHashNone | SigHashAnyoneCanPay, []bool{true, true, true, true, true, false}},
+		{SigHashSingle | SigHashAnyoneCanPay, []bool{false, true, true, true, true, false}},
+	}
+
+	for _, test := range tests {
+		tx := sigHashTestTx()
+
+		signature, err := tx.RequestSignature(0, subscript, test.hashType, signer)
+		if err != nil {
+			t.Fatalf("%s: %v", test.hashType, err)
+		}
+
+		if !tx.CheckSignature(0, subscript, pubKey, signature) {
+			t.Fatalf("%s: signature does not verify", test.hashType)
+		}
+
+		for i, change := range changes {
+			changed := sigHashTestTx()
+			change.change(changed)
+
+			if valid := changed.CheckSignature(0, subscript, pubKey, signature); valid != test.valid[i] {
+				t.Errorf("%s after %s: valid %t, want %t", test.hashType, change.name, valid, test.valid[i])
+			}
+		}
+	}
+}
+
+func TestSignatureHashErrors(t *testing.T) {
+	tx := sigHashTestTx()
+	tx.Results = tx.Results[:1]
+
+	if _, err := tx.SignatureHash(1, nil, SigHashSingle); err != core.ErrSigHashSingle {
+		t.Errorf("SINGLE without a matching result: %v", err)
+	}
+
+	for _, hashType := range []SigHashType{0, 0x04, 0x41, 0x81 | 0x10} {
+		if _, err := tx.SignatureHash(0, nil, hashType); err != core.ErrUnknownSigHashType {
+			t.Errorf("hash type %#x: %v", byte(hashType), err)
+		}
+	}
+}
+
+func TestParseSigHashType(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashType SigHashType
+		err      error
+	}{
+		{"ALL", SigHashAll, nil},
+		{"none", SigHashNone, nil},
+		{"SINGLE|ANYONECANPAY", SigHashSingle | SigHashAnyoneCanPay, nil},
+		{"ALL|NONE", 0, core.ErrUnknownSigHashType},
+		{"ANYONECANPAY", 0, core.ErrUnknownSigHashType},
+		{"", 0, core.ErrUnknownSigHashType},
+	}
+
+	for _, test := range tests {
+		hashType, err := ParseSigHashType(test.name)
+		if hashType != test.hashType || err != test.err {
+			t.Errorf("%q: got %s, %v", test.name, hashType, err)
+		}
+
+		if err == nil && hashType.String() != strings.ToUpper(test.name) {
+			t.Errorf("%q: printed as %s", test.name, hashType)
+		}
+	}
+}
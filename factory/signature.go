@@ -0,0 +1,31 @@
+package factory
+
+import (
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+// VerifyHash checks signature with the scheme the public key is encoded for.
+func VerifyHash(pubKey, hash, signature []byte) bool {
+	verifier, err := wallet.ParsePublicKey(pubKey)
+	if err != nil {
+		return false
+	}
+
+	return verifier.Verify(hash, signature)
+}
+
+// CheckSignatureEncoding accepts a signature with its hash type appended only
+// in the encoding the scheme of pubKey produces.
+func CheckSignatureEncoding(signature, pubKey []byte) error {
+	verifier, err := wallet.ParsePublicKey(pubKey)
+	if err != nil {
+		return err
+	}
+
+	if len(signature) == 0 || !SigHashType(signature[len(signature)-1]).valid() {
+		return core.ErrUnknownSigHashType
+	}
+
+	return verifier.CheckEncoding(signature[:len(signature)-1])
+}
@@ -2,51 +2,96 @@ package factory
 
 import (
 	"bytes"
-	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
-	"encoding/gob"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"math/big"
 	"strings"
 
 	"github.com/wilmacedo/willchain-go/core"
 	wData "github.com/wilmacedo/willchain-go/data"
-	"github.com/wilmacedo/willchain-go/utils"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/factory/wire"
 	"github.com/wilmacedo/willchain-go/wallet"
 )
 
 type Transaction struct {
+	Version  uint32
 	ID       []byte
 	Requests []TXRequest
 	Results  []TXResult
+	LockTime int64
 }
 
 type TXRequest struct {
-	ID        []byte
-	Out       int
-	Signature []byte
-	PubKey    []byte
+	ID              []byte
+	Out             int
+	UnlockingScript []byte
+	Sequence        uint32
 }
 
 type TXResult struct {
-	Value      int
-	PubKeyHash []byte
+	Value         int
+	LockingScript []byte
+}
+
+// Payment pays Amount to Address. A payment with Data is an unspendable,
+// zero value output carrying it instead.
+type Payment struct {
+	Address string
+	Amount  int
+	Data    []byte
 }
 
 type TXResults struct {
-	Results []TXResults
+	Height  int
+	Time    int64
+	Indexes []int
+	Results []TXResult
 }
 
+// CalculateHash is the transaction ID: the hash of the transaction without
+// its witness section, so changing signatures never changes it.
 func (tx *Transaction) CalculateHash() []byte {
-	var hash [32]byte
+	switch tx.Version {
+	case LegacyVersion:
+		return tx.ID
+	case InlineScriptVersion:
+		return tx.inlineScriptHash()
+	}
+
+	w := wire.NewWriter()
+	tx.encode(w, false)
+
+	hash := sha256.Sum256(w.Bytes())
+
+	return hash[:]
+}
 
+// WitnessHash commits to the whole transaction, witness included.
+func (tx *Transaction) WitnessHash() []byte {
+	hash := sha256.Sum256(tx.Serialize())
+
+	return hash[:]
+}
+
+// inlineScriptHash is the ID of transactions written before the witness
+// section: the hash with every unlocking script removed except the data of
+// the coinbase.
+func (tx *Transaction) inlineScriptHash() []byte {
 	txCopy := *tx
-	txCopy.ID = []byte{}
+	txCopy.Requests = make([]TXRequest, len(tx.Requests))
+
+	for i, req := range tx.Requests {
+		if !tx.IsCoinbase() {
+			req.UnlockingScript = nil
+		}
+
+		txCopy.Requests[i] = req
+	}
 
-	hash = sha256.Sum256(txCopy.Serialize())
+	hash := sha256.Sum256(txCopy.Serialize())
 
 	return hash[:]
 }
@@ -61,30 +106,32 @@ func (tx *Transaction) TrimmedCopy() Transaction {
 
 	for _, req := range tx.Requests {
 		requests = append(requests, TXRequest{
-			ID:        req.ID,
-			Out:       req.Out,
-			Signature: nil,
-			PubKey:    nil,
+			ID:              req.ID,
+			Out:             req.Out,
+			UnlockingScript: nil,
+			Sequence:        req.Sequence,
 		})
 	}
 
 	for _, res := range tx.Results {
 		results = append(results, TXResult{
-			Value:      res.Value,
-			PubKeyHash: nil,
+			Value:         res.Value,
+			LockingScript: res.LockingScript,
 		})
 	}
 
 	txCopy := Transaction{
+		Version:  tx.Version,
 		ID:       tx.ID,
 		Requests: requests,
 		Results:  results,
+		LockTime: tx.LockTime,
 	}
 
 	return txCopy
 }
 
-func (tx *Transaction) Sign(privateKey ecdsa.PrivateKey, prevTxs map[string]Transaction) {
+func (tx *Transaction) Sign(w wallet.Wallet, prevTxs map[string]Transaction) {
 	if tx.IsCoinbase() {
 		return
 	}
@@ -95,39 +142,62 @@ func (tx *Transaction) Sign(privateKey ecdsa.PrivateKey, prevTxs map[string]Tran
 		}
 	}
 
-	txCopy := tx.TrimmedCopy()
+	for reqId := range tx.Requests {
+		core.Handle(tx.SignRequest(reqId, w, prevTxs, SigHashAll))
+	}
+}
 
-	for reqId, req := range txCopy.Requests {
-		prevTx := prevTxs[hex.EncodeToString(req.ID)]
-		txCopy.Requests[reqId].Signature = nil
-		txCopy.Requests[reqId].PubKey = prevTx.Results[req.Out].PubKeyHash
-		txCopy.ID = txCopy.CalculateHash()
-		txCopy.Requests[reqId].PubKey = nil
+// SignRequest unlocks the pay to public key hash result spent by the request
+// at reqId with the key of w.
+func (tx *Transaction) SignRequest(reqId int, w wallet.Wallet, prevTxs map[string]Transaction, hashType SigHashType) error {
+	req := tx.Requests[reqId]
 
-		r, s, err := ecdsa.Sign(rand.Reader, &privateKey, txCopy.ID)
-		core.Handle(err)
+	prevTx := prevTxs[hex.EncodeToString(req.ID)]
+	if prevTx.ID == nil {
+		return core.ErrNilPreviousTransactions
+	}
 
-		signature := append(r.Bytes(), s.Bytes()...)
+	locking := prevTx.Results[req.Out].LockingScript
 
-		tx.Requests[reqId].Signature = signature
+	signature, err := tx.RequestSignature(reqId, locking, hashType, w.Signer())
+	if err != nil {
+		return err
 	}
-}
 
-func (req *TXRequest) UsesKey(pubKeyHash []byte) bool {
-	lockingHash := wallet.PublicKeyHash(req.PubKey)
+	tx.Requests[reqId].UnlockingScript = script.UnlockPubKeyHash(signature, w.PublicKey)
 
-	return bytes.Equal(lockingHash, pubKeyHash)
+	return nil
 }
 
 func (res *TXResult) Lock(address []byte) {
-	pubKeyHash := utils.Base58Decode(address)
-	pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-wallet.ChecksumLength]
+	res.LockingScript = AddressScript(string(address))
+}
 
-	res.PubKeyHash = pubKeyHash
+// AddressScript is the locking script that pays to address.
+func AddressScript(address string) []byte {
+	version, hash, err := wallet.ParseAddress(address)
+	core.Handle(err)
+
+	switch version {
+	case wallet.PubKeyHashVersion, wallet.Ed25519PubKeyHashVersion:
+		return script.PayToPubKeyHash(hash)
+	case wallet.ScriptHashVersion:
+		return script.PayToScriptHash(hash)
+	}
+
+	core.Handle(core.ErrInvalidAddress)
+
+	return nil
+}
+
+func (res *TXResult) IsLockedWith(lockingScript []byte) bool {
+	return bytes.Equal(res.LockingScript, lockingScript)
 }
 
 func (res *TXResult) IsLockWithKey(pubKeyHash []byte) bool {
-	return bytes.Equal(res.PubKeyHash, pubKeyHash)
+	lockingHash, ok := script.ExtractPubKeyHash(res.LockingScript)
+
+	return ok && bytes.Equal(lockingHash, pubKeyHash)
 }
 
 func (tx *Transaction) Verify(prevTxs map[string]Transaction) bool {
@@ -141,97 +211,149 @@ func (tx *Transaction) Verify(prevTxs map[string]Transaction) bool {
 		}
 	}
 
-	txCopy := tx.TrimmedCopy()
-	curve := elliptic.P256()
-
+	var spends []spend
 	for reqId, req := range tx.Requests {
 		prevTx := prevTxs[hex.EncodeToString(req.ID)]
-		txCopy.Requests[reqId].Signature = nil
-		txCopy.Requests[reqId].PubKey = prevTx.Results[req.Out].PubKeyHash
-		txCopy.ID = txCopy.CalculateHash()
-		txCopy.Requests[reqId].PubKey = nil
 
-		r := big.Int{}
-		s := big.Int{}
-		signLen := len(req.Signature)
+		spends = append(spends, spend{tx: tx, reqId: reqId, prevResult: prevTx.Results[req.Out]})
+	}
 
-		r.SetBytes(req.Signature[:(signLen / 2)])
-		s.SetBytes(req.Signature[(signLen / 2):])
+	return verifySpends(spends, nil) == nil
+}
 
-		x := big.Int{}
-		y := big.Int{}
-		keyLen := len(req.PubKey)
+func (tx *Transaction) VerifyRequest(reqId int, prevResult TXResult) bool {
+	return tx.ExecuteRequest(reqId, prevResult) == nil
+}
 
-		x.SetBytes(req.PubKey[:(keyLen / 2)])
-		y.SetBytes(req.PubKey[(keyLen / 2):])
+// ExecuteRequest runs the unlocking script of the request at reqId against
+// the locking script of the result it spends.
+func (tx *Transaction) ExecuteRequest(reqId int, prevResult TXResult) error {
+	return tx.executeRequest(reqId, prevResult, nil)
+}
 
-		rawPubKey := ecdsa.PublicKey{
-			Curve: curve,
-			X:     &x,
-			Y:     &y,
-		}
+func (tx *Transaction) executeRequest(reqId int, prevResult TXResult, cache *SigCache) error {
+	checker := requestChecker{tx: tx, reqId: reqId, cache: cache}
 
-		if !ecdsa.Verify(&rawPubKey, txCopy.ID, &r, &s) {
-			return false
-		}
+	return script.Execute(tx.Requests[reqId].UnlockingScript, prevResult.LockingScript, checker)
+}
+
+type requestChecker struct {
+	tx    *Transaction
+	reqId int
+	cache *SigCache
+}
+
+// CheckSig fails the script on signatures and public keys that are not
+// canonically encoded instead of only returning false for them.
+func (c requestChecker) CheckSig(signature, pubKey, subscript []byte) (bool, error) {
+	if err := CheckSignatureEncoding(signature, pubKey); err != nil {
+		return false, err
 	}
 
-	return true
+	return c.tx.checkSignature(c.reqId, subscript, pubKey, signature, c.cache), nil
+}
+
+func (c requestChecker) CheckLockTime(lockTime int64) bool {
+	return c.tx.checkLockTime(c.reqId, lockTime)
 }
 
 func (tx *Transaction) String() string {
 	var lines []string
 
 	lines = append(lines, fmt.Sprintf("	Transaction %x:", tx.ID))
+	lines = append(lines, fmt.Sprintf("		Witness hash: %x", tx.WitnessHash()))
+	lines = append(lines, fmt.Sprintf("		Version: %d", tx.Version))
+	lines = append(lines, fmt.Sprintf("		LockTime: %d", tx.LockTime))
 
 	for i, req := range tx.Requests {
 		lines = append(lines, fmt.Sprintf("		Request %d:", i))
 		lines = append(lines, fmt.Sprintf("			TXID: %x", req.ID))
 		lines = append(lines, fmt.Sprintf("			Out: %d", req.Out))
-		lines = append(lines, fmt.Sprintf("			Signature: %x", req.Signature))
-		lines = append(lines, fmt.Sprintf("			PubKey: %x", req.PubKey))
+		switch {
+		case tx.IsCoinbase():
+			if len(req.UnlockingScript) > 0 {
+				lines = append(lines, fmt.Sprintf("			Data: %x", req.UnlockingScript))
+			}
+		case tx.Version >= WitnessVersion:
+			lines = append(lines, fmt.Sprintf("			Witness: %s", script.Disassemble(req.UnlockingScript)))
+		default:
+			lines = append(lines, fmt.Sprintf("			Script: %s", script.Disassemble(req.UnlockingScript)))
+		}
+		lines = append(lines, fmt.Sprintf("			Sequence: %d", req.Sequence))
 	}
 
 	for i, res := range tx.Results {
 		lines = append(lines, fmt.Sprintf("		Result %d:", i))
 		lines = append(lines, fmt.Sprintf("			Value: %d", res.Value))
-		lines = append(lines, fmt.Sprintf("			Script: %x", res.PubKeyHash))
+		lines = append(lines, fmt.Sprintf("			Script: %s", script.Disassemble(res.LockingScript)))
 	}
 
 	return strings.Join(lines, "\n")
 }
 
-func (tx *Transaction) Serialize() []byte {
-	var result bytes.Buffer
-	encoder := gob.NewEncoder(&result)
-
-	err := encoder.Encode(tx)
-	core.Handle(err)
+type scriptJSON struct {
+	Asm string `json:"asm"`
+	Hex string `json:"hex"`
+}
 
-	return result.Bytes()
+type requestJSON struct {
+	TXID     string     `json:"txid"`
+	Out      int        `json:"out"`
+	Script   scriptJSON `json:"script"`
+	Sequence uint32     `json:"sequence"`
 }
 
-func (ress TXResults) Serialize() []byte {
-	var buffer bytes.Buffer
+type resultJSON struct {
+	Value  int        `json:"value"`
+	Script scriptJSON `json:"script"`
+}
 
-	encode := gob.NewEncoder(&buffer)
-	err := encode.Encode(ress)
-	core.Handle(err)
+type transactionJSON struct {
+	ID       string        `json:"id"`
+	Hash     string        `json:"hash"`
+	Version  uint32        `json:"version"`
+	LockTime int64         `json:"locktime"`
+	Requests []requestJSON `json:"requests"`
+	Results  []resultJSON  `json:"results"`
+}
 
-	return buffer.Bytes()
+func newScriptJSON(code []byte) scriptJSON {
+	return scriptJSON{
+		Asm: script.Disassemble(code),
+		Hex: hex.EncodeToString(code),
+	}
 }
 
-func DeserializeResults(data []byte) TXResults {
-	var results TXResults
-	decoder := gob.NewDecoder(bytes.NewBuffer(data))
+func (tx *Transaction) MarshalJSON() ([]byte, error) {
+	content := transactionJSON{
+		ID:       hex.EncodeToString(tx.ID),
+		Hash:     hex.EncodeToString(tx.WitnessHash()),
+		Version:  tx.Version,
+		LockTime: tx.LockTime,
+		Requests: []requestJSON{},
+		Results:  []resultJSON{},
+	}
 
-	err := decoder.Decode(&results)
-	core.Handle(err)
+	for _, req := range tx.Requests {
+		content.Requests = append(content.Requests, requestJSON{
+			TXID:     hex.EncodeToString(req.ID),
+			Out:      req.Out,
+			Script:   newScriptJSON(req.UnlockingScript),
+			Sequence: req.Sequence,
+		})
+	}
+
+	for _, res := range tx.Results {
+		content.Results = append(content.Results, resultJSON{
+			Value:  res.Value,
+			Script: newScriptJSON(res.LockingScript),
+		})
+	}
 
-	return results
+	return json.Marshal(content)
 }
 
-func CoinbaseTX(to, data string) *Transaction {
+func CoinbaseTX(to, data string, fees int) *Transaction {
 	if data == "" {
 		randData := make([]byte, 24)
 		_, err := rand.Read(randData)
@@ -241,79 +363,211 @@ func CoinbaseTX(to, data string) *Transaction {
 	}
 
 	txReq := TXRequest{
-		ID:        []byte{},
-		Out:       -1,
-		Signature: nil,
-		PubKey:    []byte(data),
+		ID:              []byte{},
+		Out:             -1,
+		UnlockingScript: nil,
+		Sequence:        SequenceFinal,
 	}
 
-	txResp := NewTXResult(wData.INITIAL_GENESIS_REWARD, to)
+	txResp := NewTXResult(wData.INITIAL_GENESIS_REWARD+fees, to)
 
+	// the data goes to a data result rather than the witness so that it is
+	// part of the ID and keeps every coinbase unique
 	tx := &Transaction{
+		Version:  TransactionVersion,
 		ID:       nil,
 		Requests: []TXRequest{txReq},
-		Results:  []TXResult{*txResp},
+		Results:  []TXResult{*txResp, *NewDataResult([]byte(data))},
 	}
 	tx.ID = tx.CalculateHash()
 
 	return tx
 }
 
-func NewTransaction(from, to string, amount int, chain *Blockchain) *Transaction {
+func NewTransaction(from string, payments []Payment, fee int, change string, lockTime int64, selector CoinSelector, chain *Blockchain) *Transaction {
 	var requests []TXRequest
 	var results []TXResult
 
+	if len(payments) == 0 {
+		core.Handle(core.ErrNoPayments)
+	}
+
+	if fee < 0 {
+		core.Handle(core.ErrInvalidFee)
+	}
+
+	amount := fee
+	for _, payment := range payments {
+		results = append(results, paymentResult(payment))
+		amount += payment.Amount
+	}
+
 	wallets, err := wallet.CreateWallets()
 	core.Handle(err)
 
 	w := wallets.GetWallet(from)
-	pubKeyHash := wallet.PublicKeyHash(w.PublicKey)
+	lockingScript := script.PayToPubKeyHash(wallet.PublicKeyHash(w.PublicKey))
 
-	acc, validResults := chain.FindSpendableResults(pubKeyHash, amount)
+	utxos := UTXOSet{chain}
 
-	if acc < amount {
-		core.Handle(core.ErrEnoughFunds)
+	// even a transaction only carrying data needs an input
+	target := amount
+	if target == 0 {
+		target = 1
 	}
 
-	for txhash, res := range validResults {
-		txHash, err := hex.DecodeString(txhash)
-		core.Handle(err)
+	acc, selected, err := utxos.FindSpendableResults(lockingScript, target, selector)
+	core.Handle(err)
 
-		for _, rs := range res {
-			request := TXRequest{
-				ID:        txHash,
-				Out:       rs,
-				Signature: nil,
-				PubKey:    w.PublicKey,
-			}
-			requests = append(requests, request)
+	for _, unspent := range selected {
+		request := TXRequest{
+			ID:              unspent.TxID,
+			Out:             unspent.Index,
+			UnlockingScript: nil,
+			Sequence:        lockTimeSequence(lockTime),
 		}
+		requests = append(requests, request)
 	}
 
-	results = append(results, *NewTXResult(amount, to))
+	if change == "" {
+		change = from
+	}
 
 	if acc > amount {
-		results = append(results, *NewTXResult(acc-amount, to))
+		results = append(results, *NewTXResult(acc-amount, change))
 	}
 
 	tx := Transaction{
+		Version:  TransactionVersion,
 		ID:       nil,
 		Requests: requests,
 		Results:  results,
+		LockTime: lockTime,
 	}
 	tx.ID = tx.CalculateHash()
-	chain.SignTransaction(&tx, w.PrivateKey)
+	chain.SignTransaction(&tx, w)
 
 	return &tx
 }
 
+// NewRawTransaction builds an unsigned transaction. A sequence of zero picks
+// the default, otherwise it is set on every request.
+func NewRawTransaction(inputs []Outpoint, payments []Payment, lockTime int64, sequence uint32) *Transaction {
+	var requests []TXRequest
+	var results []TXResult
+
+	if len(inputs) == 0 {
+		core.Handle(core.ErrNoInputs)
+	}
+
+	if len(payments) == 0 {
+		core.Handle(core.ErrNoPayments)
+	}
+
+	if sequence == 0 {
+		sequence = lockTimeSequence(lockTime)
+	}
+
+	for _, input := range inputs {
+		requests = append(requests, TXRequest{
+			ID:              input.TxID,
+			Out:             input.Index,
+			UnlockingScript: nil,
+			Sequence:        sequence,
+		})
+	}
+
+	for _, payment := range payments {
+		results = append(results, paymentResult(payment))
+	}
+
+	tx := &Transaction{
+		Version:  TransactionVersion,
+		ID:       nil,
+		Requests: requests,
+		Results:  results,
+		LockTime: lockTime,
+	}
+	tx.ID = tx.CalculateHash()
+
+	return tx
+}
+
+// CombineRawTransactions merges the requests of transactions signed apart,
+// like pledges signed with SigHashAnyoneCanPay. Results and lock time are
+// taken from the first transaction, a request already present only fills in
+// a missing unlocking script.
+func CombineRawTransactions(txs []*Transaction) *Transaction {
+	if len(txs) == 0 {
+		core.Handle(core.ErrNoInputs)
+	}
+
+	combined := txs[0].TrimmedCopy()
+	for i, req := range txs[0].Requests {
+		combined.Requests[i].UnlockingScript = req.UnlockingScript
+	}
+
+	for _, tx := range txs[1:] {
+	Requests:
+		for _, req := range tx.Requests {
+			for i, existing := range combined.Requests {
+				if bytes.Equal(existing.ID, req.ID) && existing.Out == req.Out {
+					if existing.UnlockingScript == nil {
+						combined.Requests[i].UnlockingScript = req.UnlockingScript
+					}
+
+					continue Requests
+				}
+			}
+
+			combined.Requests = append(combined.Requests, req)
+		}
+	}
+
+	combined.ID = combined.CalculateHash()
+
+	return &combined
+}
+
+// lockTimeSequence is the default request sequence. A lock time is only
+// enforced when some request is not final.
+func lockTimeSequence(lockTime int64) uint32 {
+	if lockTime != 0 {
+		return SequenceFinal - 1
+	}
+
+	return SequenceFinal
+}
+
 func NewTXResult(value int, address string) *TXResult {
 	tx := &TXResult{
-		Value:      value,
-		PubKeyHash: nil,
+		Value:         value,
+		LockingScript: nil,
 	}
 
 	tx.Lock([]byte(address))
 
 	return tx
 }
+
+func NewDataResult(data []byte) *TXResult {
+	lockingScript, err := script.NullData(data)
+	core.Handle(err)
+
+	return &TXResult{
+		Value:         0,
+		LockingScript: lockingScript,
+	}
+}
+
+func paymentResult(payment Payment) TXResult {
+	if payment.Data != nil {
+		return *NewDataResult(payment.Data)
+	}
+
+	if payment.Amount <= 0 {
+		core.Handle(core.ErrInvalidAmount)
+	}
+
+	return *NewTXResult(payment.Amount, payment.Address)
+}
@@ -0,0 +1,181 @@
+package factory
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/wilmacedo/willchain-go/factory/merkle"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+// TxProof shows a transaction is in a block without the rest of the block:
+// the block header, the transaction and its merkle path to the root of the
+// header.
+type TxProof struct {
+	Header      *Header
+	Transaction *Transaction
+	Proof       *merkle.Proof
+}
+
+// TxProof builds the proof of a transaction of the chain.
+func (chain *Blockchain) TxProof(txID []byte) (*TxProof, error) {
+	block, _, err := chain.FindTransactionBlock(txID)
+	if err != nil {
+		return nil, err
+	}
+
+	return block.TxProof(txID)
+}
+
+// TxProof builds the proof of one of the transactions of the block.
+func (block *Block) TxProof(txID []byte) (*TxProof, error) {
+	return block.txProof(block.Header(), txID)
+}
+
+func (block *Block) txProof(header *Header, txID []byte) (*TxProof, error) {
+	proof, err := block.TransactionProof(txID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TxProof{
+		Header:      header,
+		Transaction: block.Transactions[proof.Index],
+		Proof:       proof,
+	}, nil
+}
+
+// ScriptProofs returns, oldest first, the proofs of the transactions paying
+// to one of the locking scripts and of those spending their results. It is
+// what a light client needs to work out its balance. Transactions of legacy
+// blocks are left out, no light client could verify their proofs.
+func (chain *Blockchain) ScriptProofs(lockingScripts [][]byte) ([]*TxProof, error) {
+	var blocks []*Block
+	var proofs []*TxProof
+
+	scripts := make(map[string]bool)
+	for _, lockingScript := range lockingScripts {
+		scripts[string(lockingScript)] = true
+	}
+
+	iter := chain.Iterator()
+	for {
+		block := iter.Next()
+		blocks = append(blocks, block)
+
+		if len(block.PreviousHash) == 0 {
+			break
+		}
+	}
+
+	tracked := make(map[string]bool)
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+		header := block.Header()
+
+		for _, tx := range block.Transactions {
+			relevant := false
+
+			for _, req := range tx.Requests {
+				if tracked[fmt.Sprintf("%x:%d", req.ID, req.Out)] {
+					relevant = true
+				}
+			}
+
+			for resId, res := range tx.Results {
+				if scripts[string(res.LockingScript)] {
+					tracked[fmt.Sprintf("%x:%d", tx.ID, resId)] = true
+					relevant = true
+				}
+			}
+
+			if !relevant || block.Version == LegacyVersion {
+				continue
+			}
+
+			proof, err := block.txProof(header, tx.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			proofs = append(proofs, proof)
+		}
+	}
+
+	return proofs, nil
+}
+
+// Verify checks the proof of work of the header and the merkle path of the
+// transaction. It says nothing about the block being part of the chain.
+// Proofs in legacy blocks can't be verified, their hash is not derived.
+func (txProof *TxProof) Verify() bool {
+	leaf := txProof.Header.Block.merkleLeaf(txProof.Transaction)
+
+	return txProof.Header.Block.Version != LegacyVersion && txProof.Header.Validate() && merkle.VerifyProof(txProof.Header.MerkleRoot, leaf, txProof.Proof)
+}
+
+// TxProof layout: the header, the transaction, the leaf index and leaf count
+// varints, then the varint count of sibling bytes.
+func (txProof *TxProof) Serialize() []byte {
+	w := wire.NewWriter()
+
+	txProof.Header.encode(w)
+	txProof.Transaction.encode(w, true)
+
+	w.WriteVarInt(uint64(txProof.Proof.Index))
+	w.WriteVarInt(uint64(txProof.Proof.Leaves))
+	w.WriteVarInt(uint64(len(txProof.Proof.Siblings)))
+	for _, sibling := range txProof.Proof.Siblings {
+		w.WriteBytes(sibling)
+	}
+
+	return w.Bytes()
+}
+
+func DeserializeTxProof(data []byte) (*TxProof, error) {
+	var err error
+
+	r := wire.NewReader(data)
+	txProof := &TxProof{Proof: &merkle.Proof{}}
+
+	txProof.Header, err = decodeHeader(r, false)
+	if err != nil {
+		return nil, err
+	}
+
+	txProof.Transaction, err = decodeTransaction(r, false)
+	if err != nil {
+		return nil, err
+	}
+
+	txProof.Proof.Index = r.ReadCount()
+	txProof.Proof.Leaves = r.ReadCount()
+	txProof.Proof.Legacy = txProof.Header.Block.Version <= SerializedMerkleVersion
+
+	count := r.ReadCount()
+	for i := 0; i < count && r.Err() == nil; i++ {
+		txProof.Proof.Siblings = append(txProof.Proof.Siblings, r.ReadBytes())
+	}
+
+	return txProof, r.Finish()
+}
+
+// InChain tells if the block of the proof is the one the chain has at its
+// height.
+func (txProof *TxProof) InChain(chain *Blockchain) bool {
+	iter := chain.Iterator()
+	header := txProof.Header.Block
+
+	for {
+		block := iter.Next()
+
+		if block.Height == header.Height {
+			return bytes.Equal(block.Hash, header.Hash)
+		}
+
+		if block.Height < header.Height || len(block.PreviousHash) == 0 {
+			return false
+		}
+	}
+}
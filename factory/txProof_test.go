@@ -0,0 +1,127 @@
+package factory
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/storage"
+)
+
+func TestTxProof(t *testing.T) {
+	for _, version := range []uint32{SerializedMerkleVersion, BlockVersion} {
+		block := encodingTestBlock(version)
+
+		for _, tx := range block.Transactions {
+			name := fmt.Sprintf("version %d transaction %x", version, tx.ID)
+
+			txProof, err := block.TxProof(tx.ID)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			if !txProof.Verify() {
+				t.Errorf("%s: proof does not verify", name)
+			}
+
+			data := txProof.Serialize()
+
+			decoded, err := DeserializeTxProof(data)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			if !decoded.Verify() || !bytes.Equal(decoded.Header.Block.Hash, block.Hash) || !equalTransactions(decoded.Transaction, tx) {
+				t.Errorf("%s: proof changed on a round trip", name)
+			}
+
+			if !bytes.Equal(decoded.Serialize(), data) {
+				t.Errorf("%s: encoding changed on a round trip", name)
+			}
+
+			for size := 0; size < len(data); size++ {
+				if _, err := DeserializeTxProof(data[:size]); err == nil {
+					t.Errorf("%s: %d of %d bytes decoded", name, size, len(data))
+				}
+			}
+
+			if _, err := DeserializeTxProof(append(data, 0)); err == nil {
+				t.Errorf("%s: trailing byte accepted", name)
+			}
+		}
+
+		if _, err := block.TxProof([]byte("missing transaction")); err == nil {
+			t.Errorf("version %d: proof of a missing transaction built", version)
+		}
+	}
+}
+
+func TestTxProofTampered(t *testing.T) {
+	block := encodingTestBlock(BlockVersion)
+
+	tests := []struct {
+		name   string
+		change func(txProof *TxProof)
+	}{
+		{"other transaction", func(txProof *TxProof) { txProof.Transaction = block.Transactions[1] }},
+		{"changed result", func(txProof *TxProof) {
+			tx := *txProof.Transaction
+			tx.Results = append([]TXResult{{Value: 1000}}, tx.Results[1:]...)
+			tx.ID = tx.CalculateHash()
+			txProof.Transaction = &tx
+		}},
+		{"other index", func(txProof *TxProof) { txProof.Proof.Index = 1 }},
+		{"other merkle root", func(txProof *TxProof) { txProof.Header.MerkleRoot = sha256.New().Sum(nil) }},
+		{"no proof of work", func(txProof *TxProof) { txProof.Header.Block.Nonce++ }},
+	}
+
+	for _, test := range tests {
+		txProof, err := block.TxProof(block.Transactions[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		test.change(txProof)
+
+		if txProof.Verify() {
+			t.Errorf("%s: proof verifies", test.name)
+		}
+	}
+}
+
+// A legacy header carries its hash, so any block could be passed off as
+// one. Such proofs are neither decoded nor verified.
+func TestLegacyTxProof(t *testing.T) {
+	block := encodingTestBlock(SerializedMerkleVersion)
+
+	txProof, err := block.TxProof(block.Transactions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txProof.Header.Block.Version = LegacyVersion
+
+	if txProof.Verify() {
+		t.Error("legacy proof verifies")
+	}
+
+	if _, err := DeserializeTxProof(txProof.Serialize()); err != core.ErrLegacyHeader {
+		t.Errorf("legacy proof decoded: %v", err)
+	}
+}
+
+// Light clients are served no proofs they would fail to decode.
+func TestScriptProofsLegacy(t *testing.T) {
+	db := storage.NewMemory()
+	legacyTestChain(t, db, 3)
+
+	chain := LoadBlockchain(db)
+
+	proofs, err := chain.ScriptProofs([][]byte{script.PayToPubKeyHash([]byte("key 1"))})
+	if err != nil || len(proofs) != 0 {
+		t.Errorf("%d proofs of legacy blocks, %v", len(proofs), err)
+	}
+}
@@ -0,0 +1,211 @@
+package factory
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/storage"
+)
+
+const (
+	utxoPrefix = "utxo-"
+
+	// utxoTipKey holds the hash of the block the unspent outputs are up to.
+	utxoTipKey = "ut"
+)
+
+type UTXOSet struct {
+	Blockchain *Blockchain
+}
+
+func utxoKey(txID []byte) []byte {
+	return append([]byte(utxoPrefix), txID...)
+}
+
+func (u UTXOSet) Reindex() {
+	db := u.Blockchain.Database
+
+	batch := db.NewBatch()
+
+	iter := db.NewIterator([]byte(utxoPrefix))
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	core.Handle(iter.Error())
+
+	for txID, results := range u.Blockchain.FindUTXO() {
+		key, err := hex.DecodeString(txID)
+		core.Handle(err)
+
+		batch.Put(utxoKey(key), results.Serialize())
+	}
+
+	batch.Put([]byte(utxoTipKey), u.Blockchain.LastHash)
+
+	err := db.Write(batch)
+	core.Handle(err)
+}
+
+// CatchUp rebuilds the index when it isn't up to the tip of the chain, as
+// for chains created before it or written without the index, and tells if it
+// had to.
+func (u UTXOSet) CatchUp() (bool, error) {
+	tip, err := u.Blockchain.Database.Get([]byte(utxoTipKey))
+	if err != nil && err != core.ErrKeyNotFound {
+		return false, err
+	}
+
+	if bytes.Equal(tip, u.Blockchain.LastHash) {
+		return false, nil
+	}
+
+	u.Reindex()
+
+	return true, nil
+}
+
+// connect adds the changes the block makes to the unspent outputs to the
+// batch.
+func (u UTXOSet) connect(batch storage.Batch, block *Block) {
+	db := u.Blockchain.Database
+
+	pending := make(map[string]TXResults)
+
+	load := func(txID []byte) (TXResults, bool) {
+		if results, ok := pending[string(txID)]; ok {
+			return results, true
+		}
+
+		data, err := db.Get(utxoKey(txID))
+		if err == core.ErrKeyNotFound {
+			return TXResults{}, false
+		}
+		core.Handle(err)
+
+		return DeserializeResults(data), true
+	}
+
+	for _, tx := range block.Transactions {
+		if !tx.IsCoinbase() {
+			for _, req := range tx.Requests {
+				results, ok := load(req.ID)
+				if !ok {
+					continue
+				}
+
+				updated := TXResults{Height: results.Height, Time: results.Time}
+				for i, idx := range results.Indexes {
+					if idx != req.Out {
+						updated.Indexes = append(updated.Indexes, idx)
+						updated.Results = append(updated.Results, results.Results[i])
+					}
+				}
+
+				pending[string(req.ID)] = updated
+			}
+		}
+
+		created := TXResults{Height: block.Height, Time: block.Timestamp}
+		for idx, res := range tx.Results {
+			if script.IsUnspendable(res.LockingScript) {
+				continue
+			}
+
+			created.Indexes = append(created.Indexes, idx)
+			created.Results = append(created.Results, res)
+		}
+
+		pending[string(tx.ID)] = created
+	}
+
+	for txID, results := range pending {
+		if len(results.Results) == 0 {
+			batch.Delete(utxoKey([]byte(txID)))
+		} else {
+			batch.Put(utxoKey([]byte(txID)), results.Serialize())
+		}
+	}
+
+	batch.Put([]byte(utxoTipKey), block.Hash)
+}
+
+func (u UTXOSet) FindUnspentResults(lockingScript []byte) []UnspentResult {
+	var unspent []UnspentResult
+
+	tip := u.Blockchain.Height()
+
+	iter := u.Blockchain.Database.NewIterator([]byte(utxoPrefix))
+	for iter.Next() {
+		txID := append([]byte{}, bytes.TrimPrefix(iter.Key(), []byte(utxoPrefix))...)
+		results := DeserializeResults(iter.Value())
+
+		for i, res := range results.Results {
+			if res.IsLockedWith(lockingScript) {
+				unspent = append(unspent, UnspentResult{
+					TxID:          txID,
+					Index:         results.Indexes[i],
+					Result:        res,
+					Confirmations: tip - results.Height + 1,
+					Height:        results.Height,
+					Time:          results.Time,
+				})
+			}
+		}
+	}
+	iter.Release()
+	core.Handle(iter.Error())
+
+	sort.SliceStable(unspent, func(i, j int) bool {
+		return unspent[i].Confirmations > unspent[j].Confirmations
+	})
+
+	return unspent
+}
+
+func (u UTXOSet) FindResult(txID []byte, index int) (UnspentResult, bool) {
+	data, err := u.Blockchain.Database.Get(utxoKey(txID))
+	if err == core.ErrKeyNotFound {
+		return UnspentResult{}, false
+	}
+	core.Handle(err)
+
+	results := DeserializeResults(data)
+
+	for i, idx := range results.Indexes {
+		if idx == index {
+			return UnspentResult{
+				TxID:          txID,
+				Index:         idx,
+				Result:        results.Results[i],
+				Confirmations: u.Blockchain.Height() - results.Height + 1,
+				Height:        results.Height,
+				Time:          results.Time,
+			}, true
+		}
+	}
+
+	return UnspentResult{}, false
+}
+
+func (u UTXOSet) FindResTX(lockingScript []byte) []TXResult {
+	var resTxs []TXResult
+
+	for _, unspent := range u.FindUnspentResults(lockingScript) {
+		resTxs = append(resTxs, unspent.Result)
+	}
+
+	return resTxs
+}
+
+func (u UTXOSet) FindSpendableResults(lockingScript []byte, amount int, selector CoinSelector) (int, []UnspentResult, error) {
+	selected, err := selector.Select(u.FindUnspentResults(lockingScript), amount)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return SumResults(selected), selected, nil
+}
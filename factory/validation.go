@@ -0,0 +1,268 @@
+package factory
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/wilmacedo/willchain-go/core"
+	wData "github.com/wilmacedo/willchain-go/data"
+	"github.com/wilmacedo/willchain-go/factory/script"
+)
+
+// ValidateTransaction checks a loose transaction against the current chain
+// before it is mined in the next block and returns the fee it pays.
+func (chain *Blockchain) ValidateTransaction(tx *Transaction) (int, error) {
+	return chain.checkTransaction(tx, chain.Height()+1, time.Now().Unix())
+}
+
+func (chain *Blockchain) checkTransaction(tx *Transaction, height int, blockTime int64) (int, error) {
+	if tx.IsCoinbase() {
+		return 0, core.ErrUnexpectedCoinbase
+	}
+
+	if len(tx.Requests) == 0 {
+		return 0, core.ErrNoInputs
+	}
+
+	if len(tx.Results) == 0 {
+		return 0, core.ErrNoPayments
+	}
+
+	if !bytes.Equal(tx.ID, tx.CalculateHash()) {
+		return 0, core.ErrInvalidTransactionID
+	}
+
+	if !tx.IsFinal(height, blockTime) {
+		return 0, core.ErrTransactionNotFinal
+	}
+
+	utxos := UTXOSet{chain}
+	seen := make(map[string]bool)
+	inputs := 0
+
+	var spends []spend
+
+	for reqId, req := range tx.Requests {
+		key := fmt.Sprintf("%x:%d", req.ID, req.Out)
+		if seen[key] {
+			return 0, core.ErrDuplicateInput
+		}
+		seen[key] = true
+
+		unspent, ok := utxos.FindResult(req.ID, req.Out)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", core.ErrMissingInput, key)
+		}
+
+		if !SequenceLockMet(req.Sequence, unspent.Height, unspent.Time, height, blockTime) {
+			return 0, fmt.Errorf("%w: %s", core.ErrSequenceLockNotMet, key)
+		}
+
+		spends = append(spends, spend{tx: tx, reqId: reqId, prevResult: unspent.Result})
+		inputs += unspent.Result.Value
+	}
+
+	outputs, err := resultsValue(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if outputs > inputs {
+		return 0, core.ErrEnoughFunds
+	}
+
+	if err := verifySpends(spends, chain.SigCache); err != nil {
+		return 0, err
+	}
+
+	return inputs - outputs, nil
+}
+
+// resultsValue checks the results of tx and returns what they pay. Data
+// results carry no value, the others must.
+func resultsValue(tx *Transaction) (int, error) {
+	outputs := 0
+
+	for _, res := range tx.Results {
+		if script.IsUnspendable(res.LockingScript) {
+			if _, ok := script.ExtractNullData(res.LockingScript); !ok || res.Value != 0 {
+				return 0, core.ErrInvalidDataOutput
+			}
+
+			continue
+		}
+
+		if res.Value <= 0 {
+			return 0, core.ErrInvalidAmount
+		}
+
+		outputs += res.Value
+	}
+
+	return outputs, nil
+}
+
+// checkBlock validates the transactions of a block in one pass, as a
+// transaction may spend the results of an earlier one of the same block. It
+// rejects spending a result twice, in one transaction or in two, paying more
+// than the inputs hold, lock times not reached at the height and time of the
+// block, failing scripts and a coinbase paying more than the reward and the
+// fees of the block. Results created in the block count as confirmed at its
+// height and time.
+func (chain *Blockchain) checkBlock(block *Block) error {
+	utxos := UTXOSet{chain}
+	created := make(map[string]UnspentResult)
+	spent := make(map[string]bool)
+	fees := 0
+
+	var spends []spend
+
+	for i, tx := range block.Transactions {
+		if tx.IsCoinbase() && i > 0 {
+			return fmt.Errorf("%w: %x", core.ErrUnexpectedCoinbase, tx.ID)
+		}
+
+		if !tx.IsCoinbase() {
+			if !tx.IsFinal(block.Height, block.Timestamp) {
+				return fmt.Errorf("%w: %x", core.ErrTransactionNotFinal, tx.ID)
+			}
+
+			inputs := 0
+			seen := make(map[string]bool)
+
+			for reqId, req := range tx.Requests {
+				key := fmt.Sprintf("%x:%d", req.ID, req.Out)
+
+				if seen[key] {
+					return fmt.Errorf("%w: %s", core.ErrDuplicateInput, key)
+				} else if spent[key] {
+					return fmt.Errorf("%w: %s", core.ErrDoubleSpend, key)
+				}
+				seen[key] = true
+				spent[key] = true
+
+				prev, ok := created[key]
+				if !ok {
+					prev, ok = utxos.FindResult(req.ID, req.Out)
+				}
+
+				if !ok {
+					return fmt.Errorf("%w: %s", core.ErrMissingInput, key)
+				}
+
+				if !SequenceLockMet(req.Sequence, prev.Height, prev.Time, block.Height, block.Timestamp) {
+					return fmt.Errorf("%w: %s", core.ErrSequenceLockNotMet, key)
+				}
+
+				spends = append(spends, spend{tx: tx, reqId: reqId, prevResult: prev.Result})
+				inputs += prev.Result.Value
+			}
+
+			outputs, err := resultsValue(tx)
+			if err != nil {
+				return fmt.Errorf("%x: %w", tx.ID, err)
+			}
+
+			if outputs > inputs {
+				return fmt.Errorf("%w: %x", core.ErrEnoughFunds, tx.ID)
+			}
+
+			fees += inputs - outputs
+		}
+
+		for resId, res := range tx.Results {
+			created[fmt.Sprintf("%x:%d", tx.ID, resId)] = UnspentResult{
+				TxID:   tx.ID,
+				Index:  resId,
+				Result: res,
+				Height: block.Height,
+				Time:   block.Timestamp,
+			}
+		}
+	}
+
+	if len(block.Transactions) > 0 && block.Transactions[0].IsCoinbase() {
+		coinbase := block.Transactions[0]
+
+		reward, err := resultsValue(coinbase)
+		if err != nil {
+			return fmt.Errorf("%x: %w", coinbase.ID, err)
+		}
+
+		if reward > wData.INITIAL_GENESIS_REWARD+fees {
+			return fmt.Errorf("%w: %x pays %d of %d", core.ErrInvalidCoinbase, coinbase.ID, reward, wData.INITIAL_GENESIS_REWARD+fees)
+		}
+	}
+
+	return verifySpends(spends, chain.SigCache)
+}
+
+// spend is a request with the result it spends, the unit of work of
+// verifySpends.
+type spend struct {
+	tx         *Transaction
+	reqId      int
+	prevResult TXResult
+}
+
+func (s spend) verify(cache *SigCache) error {
+	if err := s.tx.executeRequest(s.reqId, s.prevResult, cache); err != nil {
+		req := s.tx.Requests[s.reqId]
+
+		return fmt.Errorf("request %x:%d: %w", req.ID, req.Out, err)
+	}
+
+	return nil
+}
+
+// verifySpends runs the scripts of the spends on every core. Once one fails
+// the spends not started yet are skipped and its error is returned.
+func verifySpends(spends []spend, cache *SigCache) error {
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	jobs := make(chan spend)
+	failed := make(chan struct{})
+
+	workers := runtime.NumCPU()
+	if workers > len(spends) {
+		workers = len(spends)
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for s := range jobs {
+				if err := s.verify(cache); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(failed)
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, s := range spends {
+		select {
+		case jobs <- s:
+		case <-failed:
+			break feed
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,172 @@
+package factory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/storage"
+	"github.com/wilmacedo/willchain-go/wallet"
+)
+
+const validationTestTime = 1700000000
+
+// errScriptFailed stands for whatever error the interpreter returns.
+var errScriptFailed = errors.New("script failed")
+
+// validationTestChain connects a genesis paying 20 to w without mining it,
+// as checkBlock leaves the proof of work to the callers.
+func validationTestChain(t *testing.T) (*Blockchain, *wallet.Wallet, *Transaction) {
+	w, err := wallet.MakeWallet(wallet.DefaultAlgorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain := &Blockchain{Database: storage.NewMemory(), SigCache: NewSigCache(DefaultSigCacheSize)}
+	coinbase := CoinbaseTX(string(w.Address()), "genesis", 0)
+
+	hash := sha256.Sum256([]byte("genesis"))
+	chain.connectBlock(&Block{
+		Version:      BlockVersion,
+		Hash:         hash[:],
+		Transactions: []*Transaction{coinbase},
+		Timestamp:    validationTestTime,
+	})
+
+	return chain, w, coinbase
+}
+
+// spendTestTx pays value back to w from the results of prev at outs.
+func spendTestTx(w *wallet.Wallet, prev *Transaction, value int, sequence uint32, outs ...int) *Transaction {
+	tx := &Transaction{Version: TransactionVersion}
+
+	for _, out := range outs {
+		tx.Requests = append(tx.Requests, TXRequest{ID: prev.ID, Out: out, Sequence: sequence})
+	}
+	tx.Results = []TXResult{*NewTXResult(value, string(w.Address()))}
+	tx.ID = tx.CalculateHash()
+
+	tx.Sign(*w, map[string]Transaction{hex.EncodeToString(prev.ID): *prev})
+
+	return tx
+}
+
+func TestCheckBlock(t *testing.T) {
+	chain, w, genesis := validationTestChain(t)
+	other, _ := wallet.MakeWallet(wallet.DefaultAlgorithm)
+
+	coinbase := CoinbaseTX(string(w.Address()), "block 1", 0)
+	parent := spendTestTx(w, genesis, 20, SequenceFinal, 0)
+
+	tests := []struct {
+		name string
+		txs  func() []*Transaction
+		err  error
+	}{
+		{"chain parent", func() []*Transaction {
+			return []*Transaction{parent}
+		}, nil},
+		{"chain parent relative lock met", func() []*Transaction {
+			return []*Transaction{spendTestTx(w, genesis, 20, 1, 0)}
+		}, nil},
+		{"chain parent relative lock unmet", func() []*Transaction {
+			return []*Transaction{spendTestTx(w, genesis, 20, 2, 0)}
+		}, core.ErrSequenceLockNotMet},
+		{"block parent", func() []*Transaction {
+			return []*Transaction{parent, spendTestTx(w, parent, 20, SequenceFinal, 0)}
+		}, nil},
+		{"block parent height lock unmet", func() []*Transaction {
+			return []*Transaction{parent, spendTestTx(w, parent, 20, 1, 0)}
+		}, core.ErrSequenceLockNotMet},
+		{"block parent time lock unmet", func() []*Transaction {
+			return []*Transaction{parent, spendTestTx(w, parent, 20, SequenceLockTimeIsSeconds|1, 0)}
+		}, core.ErrSequenceLockNotMet},
+		{"block parent zero lock", func() []*Transaction {
+			return []*Transaction{parent, spendTestTx(w, parent, 20, 0, 0)}
+		}, nil},
+		{"child before parent", func() []*Transaction {
+			return []*Transaction{spendTestTx(w, parent, 20, SequenceFinal, 0), parent}
+		}, core.ErrMissingInput},
+		{"duplicate input", func() []*Transaction {
+			return []*Transaction{spendTestTx(w, genesis, 20, SequenceFinal, 0, 0)}
+		}, core.ErrDuplicateInput},
+		{"double spend", func() []*Transaction {
+			return []*Transaction{parent, spendTestTx(w, genesis, 19, SequenceFinal, 0)}
+		}, core.ErrDoubleSpend},
+		{"overspend", func() []*Transaction {
+			return []*Transaction{spendTestTx(w, genesis, 21, SequenceFinal, 0)}
+		}, core.ErrEnoughFunds},
+		{"missing input", func() []*Transaction {
+			tx := spendTestTx(w, genesis, 20, SequenceFinal, 0)
+			tx.Requests[0].Out = 5
+			tx.ID = tx.CalculateHash()
+
+			return []*Transaction{tx}
+		}, core.ErrMissingInput},
+		{"not final", func() []*Transaction {
+			tx := spendTestTx(w, genesis, 20, 0, 0)
+			tx.LockTime = 2
+			tx.ID = tx.CalculateHash()
+			tx.Sign(*w, map[string]Transaction{hex.EncodeToString(genesis.ID): *genesis})
+
+			return []*Transaction{tx}
+		}, core.ErrTransactionNotFinal},
+		{"signed by another key", func() []*Transaction {
+			tx := spendTestTx(w, genesis, 20, SequenceFinal, 0)
+			tx.Sign(*other, map[string]Transaction{hex.EncodeToString(genesis.ID): *genesis})
+
+			return []*Transaction{tx}
+		}, errScriptFailed},
+	}
+
+	for _, test := range tests {
+		block := &Block{
+			Version:      BlockVersion,
+			Transactions: append([]*Transaction{coinbase}, test.txs()...),
+			PreviousHash: chain.LastHash,
+			Height:       1,
+			Timestamp:    validationTestTime + 600,
+		}
+
+		if err := chain.checkBlock(block); !errors.Is(err, test.err) && !(test.err == errScriptFailed && err != nil) {
+			t.Errorf("%s: %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestCheckBlockCoinbase(t *testing.T) {
+	chain, w, genesis := validationTestChain(t)
+	address := string(w.Address())
+
+	// Pays a fee of 5.
+	tx := spendTestTx(w, genesis, 15, SequenceFinal, 0)
+
+	tests := []struct {
+		name string
+		txs  []*Transaction
+		err  error
+	}{
+		{"reward", []*Transaction{CoinbaseTX(address, "block 1", 0)}, nil},
+		{"above the reward", []*Transaction{CoinbaseTX(address, "block 1", 1)}, core.ErrInvalidCoinbase},
+		{"reward and fees", []*Transaction{CoinbaseTX(address, "block 1", 5), tx}, nil},
+		{"below the reward and fees", []*Transaction{CoinbaseTX(address, "block 1", 4), tx}, nil},
+		{"above the reward and fees", []*Transaction{CoinbaseTX(address, "block 1", 6), tx}, core.ErrInvalidCoinbase},
+		{"second coinbase", []*Transaction{CoinbaseTX(address, "block 1", 0), CoinbaseTX(address, "block 1 again", 0)}, core.ErrUnexpectedCoinbase},
+	}
+
+	for _, test := range tests {
+		block := &Block{
+			Version:      BlockVersion,
+			Transactions: test.txs,
+			PreviousHash: chain.LastHash,
+			Height:       1,
+			Timestamp:    validationTestTime + 600,
+		}
+
+		if err := chain.checkBlock(block); !errors.Is(err, test.err) {
+			t.Errorf("%s: %v, want %v", test.name, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package wire implements the byte exact encoding shared by hashing, storage
+// and the network: little endian integers and CompactSize varints, as in
+// Bitcoin, with byte slices prefixed by their varint length.
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// MaxSliceSize bounds the length a reader accepts for one byte slice or item
+// count, so corrupt input can't make it allocate without limit.
+const MaxSliceSize = 32 * 1024 * 1024
+
+type Writer struct {
+	buffer bytes.Buffer
+}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+func (w *Writer) Bytes() []byte {
+	return w.buffer.Bytes()
+}
+
+// WriteRaw appends data as is, without a length.
+func (w *Writer) WriteRaw(data []byte) {
+	w.buffer.Write(data)
+}
+
+func (w *Writer) WriteUint8(value uint8) {
+	w.buffer.WriteByte(value)
+}
+
+func (w *Writer) WriteUint32(value uint32) {
+	var data [4]byte
+	binary.LittleEndian.PutUint32(data[:], value)
+	w.buffer.Write(data[:])
+}
+
+func (w *Writer) WriteUint64(value uint64) {
+	var data [8]byte
+	binary.LittleEndian.PutUint64(data[:], value)
+	w.buffer.Write(data[:])
+}
+
+func (w *Writer) WriteInt64(value int64) {
+	w.WriteUint64(uint64(value))
+}
+
+// WriteVarInt uses the shortest CompactSize form: one byte below 0xfd, then
+// 0xfd, 0xfe or 0xff followed by 2, 4 or 8 bytes.
+func (w *Writer) WriteVarInt(value uint64) {
+	switch {
+	case value < 0xfd:
+		w.WriteUint8(uint8(value))
+	case value <= 0xffff:
+		var data [2]byte
+		binary.LittleEndian.PutUint16(data[:], uint16(value))
+		w.WriteUint8(0xfd)
+		w.buffer.Write(data[:])
+	case value <= 0xffffffff:
+		w.WriteUint8(0xfe)
+		w.WriteUint32(uint32(value))
+	default:
+		w.WriteUint8(0xff)
+		w.WriteUint64(value)
+	}
+}
+
+func (w *Writer) WriteBytes(data []byte) {
+	w.WriteVarInt(uint64(len(data)))
+	w.buffer.Write(data)
+}
+
+// Reader keeps the first error it hits, later reads return zero values so
+// callers check Err once at the end.
+type Reader struct {
+	reader *bytes.Reader
+	err    error
+}
+
+func NewReader(data []byte) *Reader {
+	return &Reader{reader: bytes.NewReader(data)}
+}
+
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Finish reports an error if the input was not consumed exactly.
+func (r *Reader) Finish() error {
+	if r.err == nil && r.reader.Len() != 0 {
+		r.err = core.ErrMalformedEncoding
+	}
+
+	return r.err
+}
+
+func (r *Reader) read(size int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if size > r.reader.Len() {
+		r.err = core.ErrMalformedEncoding
+		return nil
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		r.err = core.ErrMalformedEncoding
+		return nil
+	}
+
+	return data
+}
+
+// ReadRaw reads size bytes written without a length.
+func (r *Reader) ReadRaw(size int) []byte {
+	return r.read(size)
+}
+
+func (r *Reader) ReadUint8() uint8 {
+	data := r.read(1)
+	if data == nil {
+		return 0
+	}
+
+	return data[0]
+}
+
+func (r *Reader) ReadUint32() uint32 {
+	data := r.read(4)
+	if data == nil {
+		return 0
+	}
+
+	return binary.LittleEndian.Uint32(data)
+}
+
+func (r *Reader) ReadUint64() uint64 {
+	data := r.read(8)
+	if data == nil {
+		return 0
+	}
+
+	return binary.LittleEndian.Uint64(data)
+}
+
+func (r *Reader) ReadInt64() int64 {
+	return int64(r.ReadUint64())
+}
+
+// ReadVarInt rejects values not written in their shortest form, so every
+// value has exactly one encoding.
+func (r *Reader) ReadVarInt() uint64 {
+	var value, min uint64
+
+	switch prefix := r.ReadUint8(); prefix {
+	case 0xfd:
+		data := r.read(2)
+		if data == nil {
+			return 0
+		}
+
+		value, min = uint64(binary.LittleEndian.Uint16(data)), 0xfd
+	case 0xfe:
+		value, min = uint64(r.ReadUint32()), 0x10000
+	case 0xff:
+		value, min = r.ReadUint64(), 0x100000000
+	default:
+		return uint64(prefix)
+	}
+
+	if r.err == nil && value < min {
+		r.err = core.ErrMalformedEncoding
+		return 0
+	}
+
+	return value
+}
+
+// ReadCount reads an item count that can't be above MaxSliceSize.
+func (r *Reader) ReadCount() int {
+	count := r.ReadVarInt()
+	if count > MaxSliceSize {
+		r.err = core.ErrMalformedEncoding
+		return 0
+	}
+
+	return int(count)
+}
+
+func (r *Reader) ReadBytes() []byte {
+	size := r.ReadCount()
+	if size == 0 || r.err != nil {
+		return nil
+	}
+
+	return r.read(size)
+}
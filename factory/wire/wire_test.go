@@ -0,0 +1,138 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+func TestVarInt(t *testing.T) {
+	tests := []struct {
+		value   uint64
+		encoded string
+	}{
+		{0, "00"},
+		{0xfc, "fc"},
+		{0xfd, "fdfd00"},
+		{0xffff, "fdffff"},
+		{0x10000, "fe00000100"},
+		{0xffffffff, "feffffffff"},
+		{0x100000000, "ff0000000001000000"},
+		{0xffffffffffffffff, "ffffffffffffffffff"},
+	}
+
+	for _, test := range tests {
+		w := NewWriter()
+		w.WriteVarInt(test.value)
+
+		if encoded := hex.EncodeToString(w.Bytes()); encoded != test.encoded {
+			t.Errorf("%d: encoded %s, want %s", test.value, encoded, test.encoded)
+		}
+
+		r := NewReader(w.Bytes())
+		if value := r.ReadVarInt(); value != test.value || r.Finish() != nil {
+			t.Errorf("%s: decoded %d, %v", test.encoded, value, r.Err())
+		}
+	}
+}
+
+func TestNonCanonicalVarInt(t *testing.T) {
+	for _, encoded := range []string{"fd0000", "fdfc00", "fe0000000000", "feffff0000", "ff0000000000000000", "ffffffffff00000000"} {
+		data, _ := hex.DecodeString(encoded)
+
+		r := NewReader(data)
+		r.ReadVarInt()
+
+		if r.Err() != core.ErrMalformedEncoding {
+			t.Errorf("%s: %v", encoded, r.Err())
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	w := NewWriter()
+	w.WriteUint8(7)
+	w.WriteUint32(0xdeadbeef)
+	w.WriteUint64(1 << 40)
+	w.WriteInt64(-5)
+	w.WriteBytes([]byte("data"))
+	w.WriteBytes(nil)
+	w.WriteRaw([]byte{1, 2})
+
+	if encoded := hex.EncodeToString(w.Bytes()); encoded != "07efbeadde0000000000010000fbffffffffffffff0464617461000102" {
+		t.Errorf("encoded %s", encoded)
+	}
+
+	r := NewReader(w.Bytes())
+
+	if value := r.ReadUint8(); value != 7 {
+		t.Errorf("uint8 %d", value)
+	}
+	if value := r.ReadUint32(); value != 0xdeadbeef {
+		t.Errorf("uint32 %#x", value)
+	}
+	if value := r.ReadUint64(); value != 1<<40 {
+		t.Errorf("uint64 %d", value)
+	}
+	if value := r.ReadInt64(); value != -5 {
+		t.Errorf("int64 %d", value)
+	}
+	if data := r.ReadBytes(); !bytes.Equal(data, []byte("data")) {
+		t.Errorf("bytes %q", data)
+	}
+	if data := r.ReadBytes(); data != nil {
+		t.Errorf("empty bytes %q", data)
+	}
+	if data := r.ReadRaw(2); !bytes.Equal(data, []byte{1, 2}) {
+		t.Errorf("raw %v", data)
+	}
+
+	if err := r.Finish(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	w := NewWriter()
+	w.WriteUint32(1)
+	w.WriteBytes([]byte("some bytes"))
+	w.WriteUint64(2)
+	data := w.Bytes()
+
+	for size := 0; size < len(data); size++ {
+		r := NewReader(data[:size])
+		r.ReadUint32()
+		r.ReadBytes()
+		r.ReadUint64()
+
+		if r.Finish() != core.ErrMalformedEncoding {
+			t.Errorf("%d of %d bytes: %v", size, len(data), r.Err())
+		}
+
+		// Reads after the first error return zero values.
+		if r.ReadUint32() != 0 || r.ReadBytes() != nil {
+			t.Errorf("%d of %d bytes: read after an error", size, len(data))
+		}
+	}
+
+	r := NewReader(append(data, 0))
+	r.ReadUint32()
+	r.ReadBytes()
+	r.ReadUint64()
+
+	if r.Finish() != core.ErrMalformedEncoding {
+		t.Error("trailing byte accepted")
+	}
+}
+
+func TestCountLimit(t *testing.T) {
+	w := NewWriter()
+	w.WriteVarInt(MaxSliceSize + 1)
+
+	r := NewReader(w.Bytes())
+	if r.ReadCount() != 0 || r.Err() != core.ErrMalformedEncoding {
+		t.Errorf("count above the limit: %v", r.Err())
+	}
+}
@@ -0,0 +1,141 @@
+// Package network carries messages between full nodes and light clients over
+// TCP. Every message is framed as in Bitcoin: magic, command, payload length
+// and checksum, then the payload.
+package network
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+const (
+	Magic uint32 = 0x4c4c4957 // "WILL"
+
+	commandSize  = 12
+	checksumSize = 4
+	headerSize   = 4 + commandSize + 4 + checksumSize
+
+	MaxPayloadSize = wire.MaxSliceSize
+)
+
+const (
+	CmdGetHeaders = "getheaders"
+	CmdHeaders    = "headers"
+	CmdGetProofs  = "getproofs"
+	CmdProofs     = "proofs"
+	CmdReject     = "reject"
+
+	CmdGetFilterHeaders = "getcfheaders"
+	CmdFilterHeaders    = "cfheaders"
+	CmdGetFilters       = "getcfilters"
+	CmdFilters          = "cfilters"
+	CmdGetBlocks        = "getblocks"
+	CmdBlocks           = "blocks"
+
+	CmdFilterLoad      = "filterload"
+	CmdFilterAdd       = "filteradd"
+	CmdFilterClear     = "filterclear"
+	CmdGetMerkleBlocks = "getmerkle"
+	CmdMerkleBlocks    = "merkleblocks"
+)
+
+// notifications get no reply when they succeed. A reject to one of them is
+// the last message of the connection.
+var notifications = map[string]bool{
+	CmdFilterLoad:  true,
+	CmdFilterAdd:   true,
+	CmdFilterClear: true,
+}
+
+type Message struct {
+	Command string
+	Payload []byte
+}
+
+func checksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+
+	return second[:checksumSize]
+}
+
+func WriteMessage(w io.Writer, msg *Message) error {
+	if len(msg.Command) > commandSize || len(msg.Payload) > MaxPayloadSize {
+		return core.ErrInvalidMessage
+	}
+
+	command := make([]byte, commandSize)
+	copy(command, msg.Command)
+
+	frame := wire.NewWriter()
+	frame.WriteUint32(Magic)
+	frame.WriteRaw(command)
+	frame.WriteUint32(uint32(len(msg.Payload)))
+	frame.WriteRaw(checksum(msg.Payload))
+	frame.WriteRaw(msg.Payload)
+
+	_, err := w.Write(frame.Bytes())
+
+	return err
+}
+
+func ReadMessage(r io.Reader) (*Message, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	reader := wire.NewReader(header)
+	magic := reader.ReadUint32()
+	command := reader.ReadRaw(commandSize)
+	length := reader.ReadUint32()
+	sum := reader.ReadRaw(checksumSize)
+
+	if magic != Magic || length > MaxPayloadSize {
+		return nil, core.ErrInvalidMessage
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(sum, checksum(payload)) {
+		return nil, core.ErrInvalidMessage
+	}
+
+	return &Message{
+		Command: string(bytes.TrimRight(command, "\x00")),
+		Payload: payload,
+	}, nil
+}
+
+// encodeItems writes a varint count of byte slices, the shape of every list
+// payload.
+func encodeItems(items [][]byte) []byte {
+	w := wire.NewWriter()
+
+	w.WriteVarInt(uint64(len(items)))
+	for _, item := range items {
+		w.WriteBytes(item)
+	}
+
+	return w.Bytes()
+}
+
+func decodeItems(payload []byte) ([][]byte, error) {
+	var items [][]byte
+
+	r := wire.NewReader(payload)
+
+	count := r.ReadCount()
+	for i := 0; i < count && r.Err() == nil; i++ {
+		items = append(items, r.ReadBytes())
+	}
+
+	return items, r.Finish()
+}
@@ -0,0 +1,101 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+func TestMessage(t *testing.T) {
+	msg := &Message{Command: CmdGetHeaders, Payload: encodeItems([][]byte{[]byte("one"), {}, []byte("three")})}
+
+	var frame bytes.Buffer
+	if err := WriteMessage(&frame, msg); err != nil {
+		t.Fatal(err)
+	}
+	data := frame.Bytes()
+
+	decoded, err := ReadMessage(bytes.NewReader(data))
+	if err != nil || decoded.Command != msg.Command || !bytes.Equal(decoded.Payload, msg.Payload) {
+		t.Fatalf("round trip: %v, %v", decoded, err)
+	}
+
+	items, err := decodeItems(decoded.Payload)
+	if err != nil || len(items) != 3 || string(items[2]) != "three" {
+		t.Errorf("items %q, %v", items, err)
+	}
+
+	for size := 0; size < len(data); size++ {
+		if _, err := ReadMessage(bytes.NewReader(data[:size])); err == nil {
+			t.Errorf("%d of %d bytes read", size, len(data))
+		}
+	}
+
+	tampered := []struct {
+		name  string
+		index int
+	}{
+		{"magic", 0},
+		{"length", 4 + commandSize},
+		{"checksum", headerSize - 1},
+		{"payload", len(data) - 1},
+	}
+
+	for _, test := range tampered {
+		changed := append([]byte{}, data...)
+		changed[test.index] ^= 1
+
+		if _, err := ReadMessage(bytes.NewReader(changed)); err == nil {
+			t.Errorf("changed %s read", test.name)
+		}
+	}
+
+	if err := WriteMessage(&frame, &Message{Command: strings.Repeat("c", commandSize+1)}); !errors.Is(err, core.ErrInvalidMessage) {
+		t.Errorf("long command: %v", err)
+	}
+}
+
+// answerPeer connects a peer to a node that reads one request and answers
+// with reply.
+func answerPeer(t *testing.T, reply *Message) *Peer {
+	client, node := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+
+	go func() {
+		defer node.Close()
+
+		if _, err := ReadMessage(node); err == nil {
+			WriteMessage(node, reply)
+		}
+	}()
+
+	return &Peer{conn: client}
+}
+
+func TestPeerRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply *Message
+		err   error
+	}{
+		{"headers", &Message{Command: CmdHeaders, Payload: encodeItems(nil)}, nil},
+		{"reject", &Message{Command: CmdReject, Payload: []byte("no")}, core.ErrRejected},
+		{"other reply", &Message{Command: CmdProofs, Payload: encodeItems(nil)}, core.ErrUnexpectedMessage},
+	}
+
+	for _, test := range tests {
+		headers, err := answerPeer(t, test.reply).GetHeaders(0)
+		if !errors.Is(err, test.err) || len(headers) != 0 {
+			t.Errorf("%s: %d headers, %v, want %v", test.name, len(headers), err, test.err)
+		}
+	}
+
+	malformed := &Message{Command: CmdHeaders, Payload: encodeItems([][]byte{[]byte("not a header")})}
+	if headers, err := answerPeer(t, malformed).GetHeaders(0); err == nil {
+		t.Errorf("malformed headers: decoded %d", len(headers))
+	}
+}
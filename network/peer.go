@@ -0,0 +1,235 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/factory/bloom"
+	"github.com/wilmacedo/willchain-go/factory/gcs"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+const requestTimeout = 30 * time.Second
+
+// Peer is the client side of a connection to a full node.
+type Peer struct {
+	conn net.Conn
+}
+
+func Dial(address string) (*Peer, error) {
+	conn, err := net.DialTimeout("tcp", address, requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Peer{conn: conn}, nil
+}
+
+func (peer *Peer) Close() error {
+	return peer.conn.Close()
+}
+
+// send writes a message the peer doesn't answer.
+func (peer *Peer) send(command string, payload []byte) error {
+	if err := peer.conn.SetDeadline(time.Now().Add(requestTimeout)); err != nil {
+		return err
+	}
+
+	return WriteMessage(peer.conn, &Message{Command: command, Payload: payload})
+}
+
+// request sends a message and waits for the answer, expected to be a reply
+// command or a reject.
+func (peer *Peer) request(command string, payload []byte, reply string) ([]byte, error) {
+	if err := peer.conn.SetDeadline(time.Now().Add(requestTimeout)); err != nil {
+		return nil, err
+	}
+
+	if err := WriteMessage(peer.conn, &Message{Command: command, Payload: payload}); err != nil {
+		return nil, err
+	}
+
+	msg, err := ReadMessage(peer.conn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg.Command {
+	case reply:
+		return msg.Payload, nil
+	case CmdReject:
+		return nil, fmt.Errorf("%w: %s", core.ErrRejected, msg.Payload)
+	}
+
+	return nil, core.ErrUnexpectedMessage
+}
+
+// GetHeaders returns the headers of the peer from the height from up, at
+// most MaxHeadersPerMessage of them.
+func (peer *Peer) GetHeaders(from int) ([]*factory.Header, error) {
+	w := wire.NewWriter()
+	w.WriteUint32(uint32(from))
+
+	payload, err := peer.request(CmdGetHeaders, w.Bytes(), CmdHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := decodeItems(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var headers []*factory.Header
+	for _, item := range items {
+		header, err := factory.DeserializeHeader(item)
+		if err != nil {
+			return nil, err
+		}
+
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
+
+// GetProofs returns the proofs of the transactions paying to the locking
+// scripts or spending what they were paid.
+func (peer *Peer) GetProofs(lockingScripts [][]byte) ([]*factory.TxProof, error) {
+	payload, err := peer.request(CmdGetProofs, encodeItems(lockingScripts), CmdProofs)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := decodeItems(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var proofs []*factory.TxProof
+	for _, item := range items {
+		proof, err := factory.DeserializeTxProof(item)
+		if err != nil {
+			return nil, err
+		}
+
+		proofs = append(proofs, proof)
+	}
+
+	return proofs, nil
+}
+
+// GetFilterHeaders returns the filter headers of the peer from the height
+// from up, at most MaxHeadersPerMessage of them.
+func (peer *Peer) GetFilterHeaders(from int) ([][]byte, error) {
+	w := wire.NewWriter()
+	w.WriteUint32(uint32(from))
+
+	payload, err := peer.request(CmdGetFilterHeaders, w.Bytes(), CmdFilterHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeItems(payload)
+}
+
+// GetFilters returns the filters of count blocks from the height from up.
+func (peer *Peer) GetFilters(from, count int) ([]*gcs.Filter, error) {
+	w := wire.NewWriter()
+	w.WriteUint32(uint32(from))
+	w.WriteUint32(uint32(count))
+
+	payload, err := peer.request(CmdGetFilters, w.Bytes(), CmdFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := decodeItems(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var filters []*gcs.Filter
+	for _, item := range items {
+		filter, err := gcs.Deserialize(item)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+// GetBlocks returns the blocks with the hashes, at most
+// MaxBlocksPerMessage of them.
+func (peer *Peer) GetBlocks(hashes [][]byte) ([]*factory.Block, error) {
+	payload, err := peer.request(CmdGetBlocks, encodeItems(hashes), CmdBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := decodeItems(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*factory.Block
+	for _, item := range items {
+		block, err := factory.DeserializeBlock(item)
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+// LoadFilter makes the peer match the blocks it sends us against filter.
+func (peer *Peer) LoadFilter(filter *bloom.Filter) error {
+	return peer.send(CmdFilterLoad, filter.Serialize())
+}
+
+func (peer *Peer) AddToFilter(data []byte) error {
+	return peer.send(CmdFilterAdd, data)
+}
+
+func (peer *Peer) ClearFilter() error {
+	return peer.send(CmdFilterClear, nil)
+}
+
+// GetMerkleBlocks returns the merkle blocks of count blocks from the height
+// from up, filtered by the loaded filter.
+func (peer *Peer) GetMerkleBlocks(from, count int) ([]*factory.MerkleBlock, error) {
+	w := wire.NewWriter()
+	w.WriteUint32(uint32(from))
+	w.WriteUint32(uint32(count))
+
+	payload, err := peer.request(CmdGetMerkleBlocks, w.Bytes(), CmdMerkleBlocks)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := decodeItems(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var merkleBlocks []*factory.MerkleBlock
+	for _, item := range items {
+		merkleBlock, err := factory.DeserializeMerkleBlock(item)
+		if err != nil {
+			return nil, err
+		}
+
+		merkleBlocks = append(merkleBlocks, merkleBlock)
+	}
+
+	return merkleBlocks, nil
+}
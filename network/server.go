@@ -0,0 +1,281 @@
+package network
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/factory/bloom"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+const (
+	MaxHeadersPerMessage      = 2000
+	MaxFiltersPerMessage      = 1000
+	MaxBlocksPerMessage       = 16
+	MaxMerkleBlocksPerMessage = 500
+)
+
+// Server is the full node side: it answers the requests of light clients
+// from its chain.
+type Server struct {
+	chain *factory.Blockchain
+}
+
+func NewServer(chain *factory.Blockchain) *Server {
+	return &Server{chain: chain}
+}
+
+// session is the state a connection keeps between requests.
+type session struct {
+	filter *bloom.Filter
+}
+
+func (server *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go server.serve(conn)
+	}
+}
+
+// serve answers the requests of a peer in order until it disconnects or
+// sends something that isn't a message.
+func (server *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	peer := &session{}
+
+	for {
+		msg, err := ReadMessage(conn)
+		if err != nil {
+			return
+		}
+
+		fmt.Printf("%s %s\n", conn.RemoteAddr(), msg.Command)
+
+		reply := server.handle(peer, msg)
+		if reply == nil {
+			continue
+		}
+
+		if err := WriteMessage(conn, reply); err != nil || notifications[msg.Command] {
+			return
+		}
+	}
+}
+
+// handle answers a reject message to failed requests, the chain code
+// panicking included.
+func (server *Server) handle(peer *session, msg *Message) (reply *Message) {
+	defer func() {
+		if r := recover(); r != nil {
+			reply = rejectMessage(fmt.Errorf("%v", r))
+		}
+	}()
+
+	switch msg.Command {
+	case CmdGetHeaders:
+		return server.handleGetHeaders(msg.Payload)
+	case CmdGetProofs:
+		return server.handleGetProofs(msg.Payload)
+	case CmdGetFilterHeaders:
+		return server.handleGetFilterHeaders(msg.Payload)
+	case CmdGetFilters:
+		return server.handleGetFilters(msg.Payload)
+	case CmdGetBlocks:
+		return server.handleGetBlocks(msg.Payload)
+	case CmdFilterLoad:
+		return peer.handleFilterLoad(msg.Payload)
+	case CmdFilterAdd:
+		return peer.handleFilterAdd(msg.Payload)
+	case CmdFilterClear:
+		peer.filter = nil
+		return nil
+	case CmdGetMerkleBlocks:
+		return server.handleGetMerkleBlocks(peer, msg.Payload)
+	}
+
+	return rejectMessage(core.ErrUnknownCommand)
+}
+
+func rejectMessage(err error) *Message {
+	return &Message{Command: CmdReject, Payload: []byte(err.Error())}
+}
+
+// getheaders payload: the height of the first header, uint32.
+func (server *Server) handleGetHeaders(payload []byte) *Message {
+	r := wire.NewReader(payload)
+	from := r.ReadUint32()
+	if err := r.Finish(); err != nil {
+		return rejectMessage(err)
+	}
+
+	var items [][]byte
+	for _, header := range server.chain.Headers(int(from), MaxHeadersPerMessage) {
+		items = append(items, header.Serialize())
+	}
+
+	return &Message{Command: CmdHeaders, Payload: encodeItems(items)}
+}
+
+// getproofs payload: the locking scripts of the client.
+func (server *Server) handleGetProofs(payload []byte) *Message {
+	scripts, err := decodeItems(payload)
+	if err != nil {
+		return rejectMessage(err)
+	}
+
+	proofs, err := server.chain.ScriptProofs(scripts)
+	if err != nil {
+		return rejectMessage(err)
+	}
+
+	var items [][]byte
+	for _, proof := range proofs {
+		items = append(items, proof.Serialize())
+	}
+
+	return &Message{Command: CmdProofs, Payload: encodeItems(items)}
+}
+
+// getcfheaders payload: the height of the first filter header, uint32.
+func (server *Server) handleGetFilterHeaders(payload []byte) *Message {
+	r := wire.NewReader(payload)
+	from := r.ReadUint32()
+	if err := r.Finish(); err != nil {
+		return rejectMessage(err)
+	}
+
+	var items [][]byte
+	for _, header := range server.chain.Headers(int(from), MaxHeadersPerMessage) {
+		filterHeader, err := server.chain.FilterHeader(header.Block.Hash)
+		if err != nil {
+			return rejectMessage(err)
+		}
+
+		items = append(items, filterHeader)
+	}
+
+	return &Message{Command: CmdFilterHeaders, Payload: encodeItems(items)}
+}
+
+// getcfilters payload: the height of the first filter and how many, both
+// uint32.
+func (server *Server) handleGetFilters(payload []byte) *Message {
+	r := wire.NewReader(payload)
+	from := r.ReadUint32()
+	count := r.ReadUint32()
+	if err := r.Finish(); err != nil {
+		return rejectMessage(err)
+	}
+
+	if count > MaxFiltersPerMessage {
+		count = MaxFiltersPerMessage
+	}
+
+	var items [][]byte
+	for _, header := range server.chain.Headers(int(from), int(count)) {
+		filter, err := server.chain.BlockFilter(header.Block.Hash)
+		if err != nil {
+			return rejectMessage(err)
+		}
+
+		items = append(items, filter.Serialize())
+	}
+
+	return &Message{Command: CmdFilters, Payload: encodeItems(items)}
+}
+
+// getblocks payload: the hashes of the blocks.
+func (server *Server) handleGetBlocks(payload []byte) *Message {
+	hashes, err := decodeItems(payload)
+	if err != nil {
+		return rejectMessage(err)
+	}
+
+	if len(hashes) > MaxBlocksPerMessage {
+		return rejectMessage(core.ErrInvalidMessage)
+	}
+
+	var items [][]byte
+	for _, hash := range hashes {
+		block, err := server.chain.Block(hash)
+		if err != nil {
+			return rejectMessage(err)
+		}
+
+		items = append(items, block.Serialize())
+	}
+
+	return &Message{Command: CmdBlocks, Payload: encodeItems(items)}
+}
+
+// filterload payload: the serialized bloom filter.
+func (peer *session) handleFilterLoad(payload []byte) *Message {
+	filter, err := bloom.Deserialize(payload)
+	if err != nil {
+		return rejectMessage(err)
+	}
+
+	peer.filter = filter
+
+	return nil
+}
+
+// filteradd payload: the element to add.
+func (peer *session) handleFilterAdd(payload []byte) *Message {
+	if peer.filter == nil {
+		return rejectMessage(core.ErrNoBloomFilter)
+	}
+
+	if len(payload) > bloom.MaxElementSize {
+		return rejectMessage(core.ErrInvalidBloomFilter)
+	}
+
+	peer.filter.Add(payload)
+
+	return nil
+}
+
+// getmerkle payload: the height of the first block and how many, both
+// uint32. The filter of the session is updated as the blocks are matched,
+// so they have to be asked for in order.
+func (server *Server) handleGetMerkleBlocks(peer *session, payload []byte) *Message {
+	r := wire.NewReader(payload)
+	from := r.ReadUint32()
+	count := r.ReadUint32()
+	if err := r.Finish(); err != nil {
+		return rejectMessage(err)
+	}
+
+	if peer.filter == nil {
+		return rejectMessage(core.ErrNoBloomFilter)
+	}
+
+	if count > MaxMerkleBlocksPerMessage {
+		count = MaxMerkleBlocksPerMessage
+	}
+
+	var items [][]byte
+	for _, header := range server.chain.Headers(int(from), int(count)) {
+		block, err := server.chain.Block(header.Block.Hash)
+		if err != nil {
+			return rejectMessage(err)
+		}
+
+		items = append(items, factory.NewMerkleBlock(block, peer.filter).Serialize())
+	}
+
+	return &Message{Command: CmdMerkleBlocks, Payload: encodeItems(items)}
+}
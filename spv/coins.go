@@ -0,0 +1,118 @@
+package spv
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+)
+
+// Coin is a result paid to one of the tracked locking scripts and not spent
+// by any of the proven transactions.
+type Coin struct {
+	Outpoint      factory.Outpoint
+	Result        factory.TXResult
+	Height        int
+	Confirmations int
+}
+
+// blockTx is a proven transaction and its position in the chain.
+type blockTx struct {
+	tx     *factory.Transaction
+	height int
+	index  int
+}
+
+// Coins checks every proof against the header chain, then replays the
+// transactions oldest first to find the results still unspent. Proofs below
+// the start of a chain synced from a checkpoint are skipped.
+func (chain *HeaderChain) Coins(proofs []*factory.TxProof, lockingScripts [][]byte) ([]Coin, error) {
+	var txs []blockTx
+
+	for _, proof := range proofs {
+		if proof.Header.Block.Height < chain.Start() {
+			continue
+		}
+
+		if !proof.Verify() || !chain.Contains(proof.Header) {
+			return nil, fmt.Errorf("%w: %x", core.ErrInvalidProof, proof.Transaction.ID)
+		}
+
+		txs = append(txs, blockTx{proof.Transaction, proof.Header.Block.Height, proof.Proof.Index})
+	}
+
+	return chain.unspent(txs, lockingScripts), nil
+}
+
+// MerkleCoins is Coins for the transactions of merkle blocks.
+func (chain *HeaderChain) MerkleCoins(merkleBlocks []*factory.MerkleBlock, lockingScripts [][]byte) ([]Coin, error) {
+	var txs []blockTx
+
+	for _, merkleBlock := range merkleBlocks {
+		indexes, err := merkleBlock.Verify()
+		if err == nil && !chain.Contains(merkleBlock.Header) {
+			err = core.ErrInvalidProof
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: block %x", err, merkleBlock.Header.Block.Hash)
+		}
+
+		for i, tx := range merkleBlock.Transactions {
+			txs = append(txs, blockTx{tx, merkleBlock.Header.Block.Height, indexes[i]})
+		}
+	}
+
+	return chain.unspent(txs, lockingScripts), nil
+}
+
+func (chain *HeaderChain) unspent(txs []blockTx, lockingScripts [][]byte) []Coin {
+	sort.SliceStable(txs, func(i, j int) bool {
+		if txs[i].height != txs[j].height {
+			return txs[i].height < txs[j].height
+		}
+
+		return txs[i].index < txs[j].index
+	})
+
+	scripts := make(map[string]bool)
+	for _, lockingScript := range lockingScripts {
+		scripts[string(lockingScript)] = true
+	}
+
+	var order []string
+	coins := make(map[string]Coin)
+
+	for _, entry := range txs {
+		tx := entry.tx
+
+		for _, req := range tx.Requests {
+			delete(coins, fmt.Sprintf("%x:%d", req.ID, req.Out))
+		}
+
+		for resId, res := range tx.Results {
+			if !scripts[string(res.LockingScript)] {
+				continue
+			}
+
+			key := fmt.Sprintf("%x:%d", tx.ID, resId)
+			order = append(order, key)
+			coins[key] = Coin{
+				Outpoint:      factory.Outpoint{TxID: tx.ID, Index: resId},
+				Result:        res,
+				Height:        entry.height,
+				Confirmations: chain.Height() - entry.height + 1,
+			}
+		}
+	}
+
+	var unspent []Coin
+	for _, key := range order {
+		if coin, ok := coins[key]; ok {
+			unspent = append(unspent, coin)
+		}
+	}
+
+	return unspent
+}
@@ -0,0 +1,156 @@
+package spv
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/network"
+)
+
+// SyncFilterHeaders downloads the filter headers of the synced headers the
+// chain doesn't have yet and returns how many it added.
+func (chain *HeaderChain) SyncFilterHeaders(peer *network.Peer) (int, error) {
+	added := 0
+
+	for len(chain.FilterHeaders) < len(chain.Headers) {
+		filterHeaders, err := peer.GetFilterHeaders(chain.Start() + len(chain.FilterHeaders))
+		if err != nil {
+			return added, err
+		}
+
+		if len(filterHeaders) == 0 {
+			break
+		}
+
+		for _, filterHeader := range filterHeaders {
+			if len(chain.FilterHeaders) == len(chain.Headers) {
+				break
+			}
+
+			chain.FilterHeaders = append(chain.FilterHeaders, filterHeader)
+			added++
+		}
+	}
+
+	return added, nil
+}
+
+// Scan downloads the filter of every block with a filter header and fetches
+// only the blocks whose filter matches the locking scripts or the results
+// paid to them. It returns the proofs of the transactions of those blocks
+// touching the scripts, ready for Coins, and the heights of the fetched
+// blocks. The node learns which blocks we fetched, not which scripts matched
+// in them.
+func (chain *HeaderChain) Scan(peer *network.Peer, lockingScripts [][]byte) ([]*factory.TxProof, []int, error) {
+	var proofs []*factory.TxProof
+	var fetched []int
+
+	scripts := make(map[string]bool)
+	for _, lockingScript := range lockingScripts {
+		scripts[string(lockingScript)] = true
+	}
+
+	items := append([][]byte{}, lockingScripts...)
+	tracked := make(map[string]bool)
+
+	// Filter headers chain from the genesis, the one below a checkpoint is
+	// taken from the peer like the others.
+	var previous []byte
+	if start := chain.Start(); start > 0 && len(chain.FilterHeaders) > 0 {
+		filterHeaders, err := peer.GetFilterHeaders(start - 1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(filterHeaders) == 0 {
+			return nil, nil, core.ErrInvalidFilter
+		}
+
+		previous = filterHeaders[0]
+	}
+
+	end := chain.Start() + len(chain.FilterHeaders)
+
+	for from := chain.Start(); from < end; from += network.MaxFiltersPerMessage {
+		count := end - from
+		if count > network.MaxFiltersPerMessage {
+			count = network.MaxFiltersPerMessage
+		}
+
+		filters, err := peer.GetFilters(from, count)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(filters) != count {
+			return nil, nil, core.ErrInvalidFilter
+		}
+
+		for i, filter := range filters {
+			height := from + i
+			header := chain.Header(height)
+			filterHeader := chain.FilterHeaders[height-chain.Start()]
+
+			if !bytes.Equal(filter.Header(previous), filterHeader) {
+				return nil, nil, fmt.Errorf("%w: height %d", core.ErrInvalidFilter, height)
+			}
+			previous = filterHeader
+
+			if !filter.MatchAny(factory.FilterKey(header.Block.Hash), items) {
+				continue
+			}
+
+			blocks, err := peer.GetBlocks([][]byte{header.Block.Hash})
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if len(blocks) != 1 || !bytes.Equal(blocks[0].Hash, header.Block.Hash) || !bytes.Equal(blocks[0].HashTransactions(), header.MerkleRoot) {
+				return nil, nil, fmt.Errorf("%w: height %d", core.ErrInvalidBlock, height)
+			}
+
+			block := blocks[0]
+			fetched = append(fetched, height)
+
+			// A node could leave our items out of a filter it commits to,
+			// the blocks we get at least have to match theirs.
+			if !bytes.Equal(block.Filter().Serialize(), filter.Serialize()) {
+				return nil, nil, fmt.Errorf("%w: height %d", core.ErrInvalidFilter, height)
+			}
+
+			for _, tx := range block.Transactions {
+				relevant := false
+
+				for _, req := range tx.Requests {
+					if tracked[string(factory.FilterOutpoint(req.ID, req.Out))] {
+						relevant = true
+					}
+				}
+
+				for resId, res := range tx.Results {
+					if scripts[string(res.LockingScript)] {
+						outpoint := factory.FilterOutpoint(tx.ID, resId)
+						tracked[string(outpoint)] = true
+						items = append(items, outpoint)
+						relevant = true
+					}
+				}
+
+				if !relevant {
+					continue
+				}
+
+				proof, err := block.TxProof(tx.ID)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				proofs = append(proofs, proof)
+			}
+		}
+	}
+
+	return proofs, fetched, nil
+}
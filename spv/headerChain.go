@@ -0,0 +1,295 @@
+// Package spv is the light client: it keeps only block headers and checks
+// the transactions of its wallet with merkle proofs against them.
+package spv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/wilmacedo/willchain-go/config"
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/factory/wire"
+)
+
+const (
+	headersFile       = "headers.data"
+	filterHeadersFile = "filterheaders.data"
+)
+
+// HeaderChain is the chain of headers synced from a full node, from the
+// genesis or from a checkpoint. FilterHeaders follow it and may lag behind.
+type HeaderChain struct {
+	Headers       []*factory.Header
+	FilterHeaders [][]byte
+
+	// Checkpoint is the header an empty chain starts from instead of the
+	// genesis.
+	Checkpoint *Checkpoint
+}
+
+// Checkpoint is a header trusted without the ones below it, which the blocks
+// of chains migrated from the legacy format can't prove.
+type Checkpoint struct {
+	Height int
+	Hash   []byte
+}
+
+// ParseCheckpoint reads a checkpoint written as height:hash.
+func ParseCheckpoint(checkpoint string) (*Checkpoint, error) {
+	parts := strings.Split(checkpoint, ":")
+	if len(parts) != 2 {
+		return nil, core.ErrInvalidCheckpoint
+	}
+
+	height, err := strconv.Atoi(parts[0])
+	if err != nil || height < 0 {
+		return nil, core.ErrInvalidCheckpoint
+	}
+
+	hash, err := hex.DecodeString(parts[1])
+	if err != nil || len(hash) != sha256.Size {
+		return nil, core.ErrInvalidCheckpoint
+	}
+
+	return &Checkpoint{Height: height, Hash: hash}, nil
+}
+
+func (checkpoint *Checkpoint) String() string {
+	return fmt.Sprintf("%d:%x", checkpoint.Height, checkpoint.Hash)
+}
+
+// readItems reads a file of a varint count of byte slices, nothing when it
+// doesn't exist.
+func readItems(path string) ([][]byte, error) {
+	var items [][]byte
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	r := wire.NewReader(content)
+
+	count := r.ReadCount()
+	for i := 0; i < count && r.Err() == nil; i++ {
+		items = append(items, r.ReadBytes())
+	}
+
+	return items, r.Finish()
+}
+
+func writeItems(path string, items [][]byte) error {
+	w := wire.NewWriter()
+
+	w.WriteVarInt(uint64(len(items)))
+	for _, item := range items {
+		w.WriteBytes(item)
+	}
+
+	return ioutil.WriteFile(path, w.Bytes(), 0644)
+}
+
+func LoadHeaderChain() (*HeaderChain, error) {
+	chain := &HeaderChain{}
+
+	items, err := readItems(config.Path(headersFile))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		header, err := factory.DeserializeHeader(item)
+		if err != nil {
+			return nil, err
+		}
+
+		chain.Headers = append(chain.Headers, header)
+	}
+
+	chain.FilterHeaders, err = readItems(config.Path(filterHeadersFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chain.FilterHeaders) > len(chain.Headers) {
+		chain.FilterHeaders = chain.FilterHeaders[:len(chain.Headers)]
+	}
+
+	return chain, nil
+}
+
+func (chain *HeaderChain) Save() error {
+	var items [][]byte
+	for _, header := range chain.Headers {
+		items = append(items, header.Serialize())
+	}
+
+	if err := config.EnsureDir(); err != nil {
+		return err
+	}
+
+	if err := writeItems(config.Path(headersFile), items); err != nil {
+		return err
+	}
+
+	return writeItems(config.Path(filterHeadersFile), chain.FilterHeaders)
+}
+
+// Start is the height of the first header, the one of the checkpoint before
+// it is synced.
+func (chain *HeaderChain) Start() int {
+	if len(chain.Headers) > 0 {
+		return chain.Headers[0].Block.Height
+	} else if chain.Checkpoint != nil {
+		return chain.Checkpoint.Height
+	}
+
+	return 0
+}
+
+// Height is the height of the tip, below Start before anything is synced.
+func (chain *HeaderChain) Height() int {
+	return chain.Start() + len(chain.Headers) - 1
+}
+
+// Header returns the header at height, nil when the chain doesn't have it.
+func (chain *HeaderChain) Header(height int) *factory.Header {
+	index := height - chain.Start()
+	if index < 0 || index >= len(chain.Headers) {
+		return nil
+	}
+
+	return chain.Headers[index]
+}
+
+func (chain *HeaderChain) Tip() *factory.Header {
+	if len(chain.Headers) == 0 {
+		return nil
+	}
+
+	return chain.Headers[len(chain.Headers)-1]
+}
+
+// Connect appends a header with a valid proof of work that extends the tip,
+// or that is the checkpoint or the genesis of an empty chain.
+func (chain *HeaderChain) Connect(header *factory.Header) error {
+	block := header.Block
+
+	if tip := chain.Tip(); tip == nil && chain.Checkpoint != nil {
+		if block.Height != chain.Checkpoint.Height || !bytes.Equal(block.Hash, chain.Checkpoint.Hash) {
+			return core.ErrHeaderNotConnected
+		}
+	} else if tip == nil {
+		if block.Height != 0 || len(block.PreviousHash) != 0 {
+			return core.ErrHeaderNotConnected
+		}
+	} else if block.Height != tip.Block.Height+1 || !bytes.Equal(block.PreviousHash, tip.Block.Hash) {
+		return core.ErrHeaderNotConnected
+	}
+
+	// Legacy headers carry their hash, nothing proves the work behind it.
+	if block.Version == factory.LegacyVersion || !header.Validate() {
+		return core.ErrInvalidBlock
+	}
+
+	chain.Headers = append(chain.Headers, header)
+
+	return nil
+}
+
+// Contains tells if the chain has the header at its height.
+func (chain *HeaderChain) Contains(header *factory.Header) bool {
+	ours := chain.Header(header.Block.Height)
+
+	return ours != nil && bytes.Equal(ours.Block.Hash, header.Block.Hash)
+}
+
+// HeaderSource is where Sync gets headers, a peer outside of tests.
+type HeaderSource interface {
+	GetHeaders(from int) ([]*factory.Header, error)
+}
+
+// maxReorgDepth is how many headers Sync drops at most to switch to the
+// branch of a peer, a var for tests.
+var maxReorgDepth = 100
+
+// Sync downloads the headers the peer has above the last maxReorgDepth ones
+// of the chain and returns how many were connected. Every block has the same
+// difficulty, so the branch with the highest tip has the most work: when the
+// peer forks from the chain, its branch replaces ours, with the filter
+// headers from the fork, only once it is proven to end higher. Until then the
+// chain is left untouched.
+func (chain *HeaderChain) Sync(peer HeaderSource) (int, error) {
+	from := chain.Height() - maxReorgDepth
+	if from < chain.Start() {
+		from = chain.Start()
+	}
+
+	// Skip the headers we share with the peer up to where it forks.
+	fork := from
+	headers, err := peer.GetHeaders(fork)
+	for err == nil && len(headers) > 0 {
+		for len(headers) > 0 && chain.Contains(headers[0]) && headers[0].Block.Height == fork {
+			headers = headers[1:]
+			fork++
+		}
+
+		if len(headers) > 0 {
+			break
+		}
+
+		headers, err = peer.GetHeaders(fork)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	// The first header is where our chains meet, unless there is none yet.
+	if fork == from && len(headers) > 0 && len(chain.Headers) > 0 {
+		if from == chain.Start() {
+			return 0, core.ErrHeaderNotConnected
+		}
+
+		return 0, fmt.Errorf("%w: peer forks below height %d", core.ErrReorgTooDeep, from)
+	}
+
+	kept := fork - chain.Start()
+	branch := &HeaderChain{Headers: append([]*factory.Header{}, chain.Headers[:kept]...), Checkpoint: chain.Checkpoint}
+
+	for err == nil && len(headers) > 0 {
+		for _, header := range headers {
+			if err = branch.Connect(header); err != nil {
+				break
+			}
+		}
+
+		if err == nil {
+			headers, err = peer.GetHeaders(branch.Height() + 1)
+		}
+	}
+
+	if branch.Height() <= chain.Height() {
+		if err == nil && branch.Height() >= fork {
+			err = fmt.Errorf("%w: peer branch from height %d ends at %d, ours at %d", core.ErrForkNotLonger, fork, branch.Height(), chain.Height())
+		}
+
+		return 0, err
+	}
+
+	chain.Headers = branch.Headers
+	if len(chain.FilterHeaders) > kept {
+		chain.FilterHeaders = chain.FilterHeaders[:kept]
+	}
+
+	return branch.Height() - fork + 1, err
+}
@@ -0,0 +1,252 @@
+package spv
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+)
+
+// testPeer serves a list of headers the way a node does, a page of at most
+// pageSize from the requested height.
+type testPeer struct {
+	headers  []*factory.Header
+	pageSize int
+}
+
+func (peer *testPeer) GetHeaders(from int) ([]*factory.Header, error) {
+	if from >= len(peer.headers) {
+		return nil, nil
+	}
+
+	end := len(peer.headers)
+	if peer.pageSize > 0 && end > from+peer.pageSize {
+		end = from + peer.pageSize
+	}
+
+	return peer.headers[from:end], nil
+}
+
+// mineTestHeader mines a header on top of previous, nil for a genesis. The
+// tag tells apart the blocks of different branches.
+func mineTestHeader(previous *factory.Header, tag string) *factory.Header {
+	var hash big.Int
+
+	coinbase := &factory.Transaction{
+		Version: factory.TransactionVersion,
+		Results: []factory.TXResult{{Value: 1, LockingScript: []byte(tag)}},
+	}
+	coinbase.ID = coinbase.CalculateHash()
+
+	block := &factory.Block{
+		Version:      factory.BlockVersion,
+		Transactions: []*factory.Transaction{coinbase},
+		PreviousHash: []byte{},
+		Timestamp:    1700000000,
+	}
+
+	if previous != nil {
+		block.PreviousHash = previous.Block.Hash
+		block.Height = previous.Block.Height + 1
+	}
+
+	pow := factory.NewProof(block)
+
+	for nonce := 0; ; nonce++ {
+		data := sha256.Sum256(pow.InitData(nonce))
+
+		if hash.SetBytes(data[:]).Cmp(pow.Target) == -1 {
+			block.Nonce, block.Hash = nonce, data[:]
+
+			return block.Header()
+		}
+	}
+}
+
+// mineTestBranch extends base with count headers.
+func mineTestBranch(base []*factory.Header, count int, tag string) []*factory.Header {
+	headers := append([]*factory.Header{}, base...)
+
+	for i := 0; i < count; i++ {
+		var previous *factory.Header
+		if len(headers) > 0 {
+			previous = headers[len(headers)-1]
+		}
+
+		headers = append(headers, mineTestHeader(previous, fmt.Sprintf("%s %d", tag, len(headers))))
+	}
+
+	return headers
+}
+
+func headerHashes(headers []*factory.Header) string {
+	var hashes []string
+	for _, header := range headers {
+		hashes = append(hashes, fmt.Sprintf("%x", header.Block.Hash[:4]))
+	}
+
+	return fmt.Sprint(hashes)
+}
+
+func TestSync(t *testing.T) {
+	main := mineTestBranch(nil, 6, "main")
+	longer := mineTestBranch(main[:4], 3, "longer")
+	shorter := mineTestBranch(main[:4], 2, "shorter")
+	other := mineTestBranch(nil, 7, "other")
+
+	garbage := append(append([]*factory.Header{}, main[:4]...), shorter[4], main[5], longer[6])
+
+	tests := []struct {
+		name         string
+		local        []*factory.Header
+		filters      int
+		peer         []*factory.Header
+		connected    int
+		err          error
+		result       []*factory.Header
+		filtersAfter int
+	}{
+		{"from nothing", nil, 0, main, 6, nil, main, 0},
+		{"catch up", main[:2], 2, main, 4, nil, main, 2},
+		{"up to date", main, 6, main, 0, nil, main, 6},
+		{"peer behind", main, 6, main[:3], 0, nil, main, 6},
+		{"longer fork", main, 6, longer, 3, nil, longer, 4},
+		{"shorter fork", main, 6, shorter, 0, core.ErrForkNotLonger, main, 6},
+		{"fork as long", main, 6, mineTestBranch(main[:4], 2, "even"), 0, core.ErrForkNotLonger, main, 6},
+		{"other genesis", main, 6, other, 0, core.ErrHeaderNotConnected, main, 6},
+		{"unconnected headers", main, 6, garbage, 0, core.ErrHeaderNotConnected, main, 6},
+	}
+
+	for _, test := range tests {
+		for _, pageSize := range []int{0, 2} {
+			name := fmt.Sprintf("%s page %d", test.name, pageSize)
+
+			chain := &HeaderChain{Headers: append([]*factory.Header{}, test.local...), FilterHeaders: make([][]byte, test.filters)}
+
+			connected, err := chain.Sync(&testPeer{headers: test.peer, pageSize: pageSize})
+			if !errors.Is(err, test.err) || connected != test.connected {
+				t.Errorf("%s: connected %d, %v, want %d, %v", name, connected, err, test.connected, test.err)
+			}
+
+			if headerHashes(chain.Headers) != headerHashes(test.result) {
+				t.Errorf("%s: headers %s, want %s", name, headerHashes(chain.Headers), headerHashes(test.result))
+			}
+
+			// Filter headers of dropped headers go with them.
+			if len(chain.FilterHeaders) != test.filtersAfter {
+				t.Errorf("%s: %d filter headers, want %d", name, len(chain.FilterHeaders), test.filtersAfter)
+			}
+		}
+	}
+}
+
+func TestSyncReorgDepth(t *testing.T) {
+	defer func(depth int) { maxReorgDepth = depth }(maxReorgDepth)
+	maxReorgDepth = 2
+
+	main := mineTestBranch(nil, 5, "main")
+
+	tests := []struct {
+		name   string
+		peer   []*factory.Header
+		err    error
+		result []*factory.Header
+	}{
+		{"within the limit", mineTestBranch(main[:3], 3, "near"), nil, nil},
+		{"deeper than the limit", mineTestBranch(main[:2], 5, "deep"), core.ErrReorgTooDeep, main},
+	}
+
+	for _, test := range tests {
+		chain := &HeaderChain{Headers: append([]*factory.Header{}, main...)}
+
+		_, err := chain.Sync(&testPeer{headers: test.peer})
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: %v, want %v", test.name, err, test.err)
+		}
+
+		result := test.result
+		if result == nil {
+			result = test.peer
+		}
+
+		if headerHashes(chain.Headers) != headerHashes(result) {
+			t.Errorf("%s: headers %s, want %s", test.name, headerHashes(chain.Headers), headerHashes(result))
+		}
+	}
+}
+
+func TestSyncCheckpoint(t *testing.T) {
+	main := mineTestBranch(nil, 6, "main")
+	longer := mineTestBranch(main[:4], 3, "longer")
+	below := mineTestBranch(main[:2], 6, "below")
+
+	checkpoint := &Checkpoint{Height: 3, Hash: main[3].Block.Hash}
+
+	tests := []struct {
+		name       string
+		checkpoint *Checkpoint
+		local      []*factory.Header
+		peer       []*factory.Header
+		connected  int
+		err        error
+		result     []*factory.Header
+	}{
+		{"from the checkpoint", checkpoint, nil, main, 3, nil, main[3:]},
+		{"catch up", checkpoint, main[3:5], main, 1, nil, main[3:]},
+		{"fork above the checkpoint", checkpoint, main[3:], longer, 3, nil, append([]*factory.Header{main[3]}, longer[4:]...)},
+		{"fork below the checkpoint", checkpoint, main[3:], below, 0, core.ErrHeaderNotConnected, main[3:]},
+		{"checkpoint not on the peer branch", checkpoint, nil, below, 0, core.ErrHeaderNotConnected, nil},
+		{"other hash", &Checkpoint{Height: 3, Hash: main[2].Block.Hash}, nil, main, 0, core.ErrHeaderNotConnected, nil},
+	}
+
+	for _, test := range tests {
+		for _, pageSize := range []int{0, 2} {
+			name := fmt.Sprintf("%s page %d", test.name, pageSize)
+
+			chain := &HeaderChain{Headers: append([]*factory.Header{}, test.local...), Checkpoint: test.checkpoint}
+
+			connected, err := chain.Sync(&testPeer{headers: test.peer, pageSize: pageSize})
+			if !errors.Is(err, test.err) || connected != test.connected {
+				t.Errorf("%s: connected %d, %v, want %d, %v", name, connected, err, test.connected, test.err)
+			}
+
+			if headerHashes(chain.Headers) != headerHashes(test.result) {
+				t.Errorf("%s: headers %s, want %s", name, headerHashes(chain.Headers), headerHashes(test.result))
+			}
+
+			if chain.Start() != test.checkpoint.Height {
+				t.Errorf("%s: starts at %d", name, chain.Start())
+			}
+		}
+	}
+}
+
+func TestParseCheckpoint(t *testing.T) {
+	hash := sha256.Sum256([]byte("checkpoint"))
+	valid := fmt.Sprintf("12:%x", hash)
+
+	checkpoint, err := ParseCheckpoint(valid)
+	if err != nil || checkpoint.Height != 12 || checkpoint.String() != valid {
+		t.Errorf("%s: %v, %v", valid, checkpoint, err)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"12",
+		fmt.Sprintf("%x", hash),
+		fmt.Sprintf("-1:%x", hash),
+		fmt.Sprintf("twelve:%x", hash),
+		fmt.Sprintf("12:%x", hash[:31]),
+		"12:" + strings.Repeat("z", 64),
+		fmt.Sprintf("12:%x:1", hash),
+	} {
+		if _, err := ParseCheckpoint(invalid); !errors.Is(err, core.ErrInvalidCheckpoint) {
+			t.Errorf("%q: %v", invalid, err)
+		}
+	}
+}
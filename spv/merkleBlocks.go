@@ -0,0 +1,74 @@
+package spv
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/wilmacedo/willchain-go/core"
+	"github.com/wilmacedo/willchain-go/factory"
+	"github.com/wilmacedo/willchain-go/factory/bloom"
+	"github.com/wilmacedo/willchain-go/factory/script"
+	"github.com/wilmacedo/willchain-go/network"
+)
+
+// outpointRoom is how many outpoints the filter is sized for on top of its
+// elements, the node adds one for every result it matches.
+const outpointRoom = 100
+
+// NewBloomFilter builds a filter with the data pushed by the locking scripts,
+// the hashes results are locked to, with a random tweak. The node adds the
+// outpoints of the results it matches, so their spenders match too.
+func NewBloomFilter(lockingScripts [][]byte, falsePositiveRate float64) (*bloom.Filter, error) {
+	var elements [][]byte
+
+	for _, lockingScript := range lockingScripts {
+		instructions, err := script.Parse(lockingScript)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, instruction := range instructions {
+			if len(instruction.Data) > 0 {
+				elements = append(elements, instruction.Data)
+			}
+		}
+	}
+
+	tweak := make([]byte, 4)
+	if _, err := rand.Read(tweak); err != nil {
+		return nil, err
+	}
+
+	filter := bloom.NewFilter(len(elements)+outpointRoom, falsePositiveRate, binary.LittleEndian.Uint32(tweak), bloom.UpdateAll)
+	for _, element := range elements {
+		filter.Add(element)
+	}
+
+	return filter, nil
+}
+
+// MerkleBlocks asks the peer for the merkle blocks of the whole header chain,
+// matched against the bloom filter loaded on the connection.
+func (chain *HeaderChain) MerkleBlocks(peer *network.Peer) ([]*factory.MerkleBlock, error) {
+	var merkleBlocks []*factory.MerkleBlock
+
+	for from := chain.Start(); from <= chain.Height(); from += network.MaxMerkleBlocksPerMessage {
+		count := chain.Height() + 1 - from
+		if count > network.MaxMerkleBlocksPerMessage {
+			count = network.MaxMerkleBlocksPerMessage
+		}
+
+		batch, err := peer.GetMerkleBlocks(from, count)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(batch) != count {
+			return nil, core.ErrInvalidBlock
+		}
+
+		merkleBlocks = append(merkleBlocks, batch...)
+	}
+
+	return merkleBlocks, nil
+}
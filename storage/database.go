@@ -0,0 +1,53 @@
+package storage
+
+// Database is the key value store the chain and its indexes live in.
+// Missing keys are reported with core.ErrKeyNotFound.
+type Database interface {
+	Reader
+
+	Put(key, value []byte) error
+	Delete(key []byte) error
+
+	NewBatch() Batch
+	// Write applies every change of the batch or none of them.
+	Write(batch Batch) error
+
+	// NewSnapshot is a frozen view of the database, unaffected by later
+	// writes until released.
+	NewSnapshot() (Snapshot, error)
+
+	Close() error
+}
+
+type Reader interface {
+	Get(key []byte) ([]byte, error)
+	Has(key []byte) (bool, error)
+
+	// NewIterator walks the keys starting with prefix in ascending order.
+	NewIterator(prefix []byte) Iterator
+}
+
+// Batch collects changes to write atomically. It only works with the
+// database that created it.
+type Batch interface {
+	Put(key, value []byte)
+	Delete(key []byte)
+	Len() int
+	Reset()
+}
+
+// Iterator starts before the first key, Next moves to the next one. Key and
+// Value are only valid until the next call to Next.
+type Iterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Release()
+	Error() error
+}
+
+type Snapshot interface {
+	Reader
+
+	Release()
+}
@@ -2,17 +2,20 @@ package storage
 
 import (
 	"os"
+	"path/filepath"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/wilmacedo/willchain-go/config"
 )
 
-const (
-	dbPath = "./tmp/blocks"
-	dbFile = "./tmp/blocks/CURRENT"
-)
+const dbDir = "blocks"
+
+// Open opens the LevelDB database of the chain in the network directory.
+func Open() (Database, error) {
+	if err := config.EnsureDir(); err != nil {
+		return nil, err
+	}
 
-func Open() (*leveldb.DB, error) {
-	db, err := leveldb.OpenFile(dbPath, nil)
+	db, err := OpenLevelDB(config.Path(dbDir))
 	if err != nil {
 		return nil, err
 	}
@@ -21,7 +24,7 @@ func Open() (*leveldb.DB, error) {
 }
 
 func Exists() bool {
-	if _, err := os.Stat(dbFile); os.IsNotExist(err) {
+	if _, err := os.Stat(filepath.Join(config.Path(dbDir), "CURRENT")); os.IsNotExist(err) {
 		return false
 	}
 
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// LevelDB is the Database kept on disk.
+type LevelDB struct {
+	db *leveldb.DB
+}
+
+type levelDBBatch struct {
+	batch *leveldb.Batch
+}
+
+type levelDBSnapshot struct {
+	snapshot *leveldb.Snapshot
+}
+
+type levelDBIterator struct {
+	iterator.Iterator
+}
+
+func OpenLevelDB(path string) (*LevelDB, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LevelDB{db: db}, nil
+}
+
+func notFound(err error) error {
+	if err == leveldb.ErrNotFound {
+		return core.ErrKeyNotFound
+	}
+
+	return err
+}
+
+func (ldb *LevelDB) Get(key []byte) ([]byte, error) {
+	value, err := ldb.db.Get(key, nil)
+
+	return value, notFound(err)
+}
+
+func (ldb *LevelDB) Has(key []byte) (bool, error) {
+	return ldb.db.Has(key, nil)
+}
+
+func (ldb *LevelDB) NewIterator(prefix []byte) Iterator {
+	return levelDBIterator{ldb.db.NewIterator(util.BytesPrefix(prefix), nil)}
+}
+
+func (ldb *LevelDB) Put(key, value []byte) error {
+	return ldb.db.Put(key, value, nil)
+}
+
+func (ldb *LevelDB) Delete(key []byte) error {
+	return ldb.db.Delete(key, nil)
+}
+
+func (ldb *LevelDB) NewBatch() Batch {
+	return &levelDBBatch{batch: new(leveldb.Batch)}
+}
+
+func (ldb *LevelDB) Write(batch Batch) error {
+	b, ok := batch.(*levelDBBatch)
+	if !ok {
+		return core.ErrForeignBatch
+	}
+
+	return ldb.db.Write(b.batch, nil)
+}
+
+func (ldb *LevelDB) NewSnapshot() (Snapshot, error) {
+	snapshot, err := ldb.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &levelDBSnapshot{snapshot: snapshot}, nil
+}
+
+func (ldb *LevelDB) Close() error {
+	return ldb.db.Close()
+}
+
+func (b *levelDBBatch) Put(key, value []byte) {
+	b.batch.Put(key, value)
+}
+
+func (b *levelDBBatch) Delete(key []byte) {
+	b.batch.Delete(key)
+}
+
+func (b *levelDBBatch) Len() int {
+	return b.batch.Len()
+}
+
+func (b *levelDBBatch) Reset() {
+	b.batch.Reset()
+}
+
+func (s *levelDBSnapshot) Get(key []byte) ([]byte, error) {
+	value, err := s.snapshot.Get(key, nil)
+
+	return value, notFound(err)
+}
+
+func (s *levelDBSnapshot) Has(key []byte) (bool, error) {
+	return s.snapshot.Has(key, nil)
+}
+
+func (s *levelDBSnapshot) NewIterator(prefix []byte) Iterator {
+	return levelDBIterator{s.snapshot.NewIterator(util.BytesPrefix(prefix), nil)}
+}
+
+func (s *levelDBSnapshot) Release() {
+	s.snapshot.Release()
+}
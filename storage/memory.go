@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// Memory is a Database held in a map, for tests and nodes that don't need
+// to outlive their process.
+type Memory struct {
+	mutex  sync.RWMutex
+	values map[string][]byte
+	shared bool
+	closed bool
+}
+
+type memoryOp struct {
+	key    string
+	value  []byte
+	delete bool
+}
+
+type memoryBatch struct {
+	db  *Memory
+	ops []memoryOp
+}
+
+// memorySnapshot shares the map of the database until the next write.
+type memorySnapshot struct {
+	values map[string][]byte
+}
+
+type memoryIterator struct {
+	keys   []string
+	values map[string][]byte
+	pos    int
+	err    error
+}
+
+func NewMemory() *Memory {
+	return &Memory{values: make(map[string][]byte)}
+}
+
+func copyBytes(data []byte) []byte {
+	return append([]byte{}, data...)
+}
+
+func getValue(values map[string][]byte, key []byte) ([]byte, error) {
+	value, ok := values[string(key)]
+	if !ok {
+		return nil, core.ErrKeyNotFound
+	}
+
+	return copyBytes(value), nil
+}
+
+func newMemoryIterator(values map[string][]byte, prefix []byte) *memoryIterator {
+	iter := &memoryIterator{values: values, pos: -1}
+
+	for key := range values {
+		if bytes.HasPrefix([]byte(key), prefix) {
+			iter.keys = append(iter.keys, key)
+		}
+	}
+
+	sort.Strings(iter.keys)
+
+	return iter
+}
+
+func (db *Memory) Get(key []byte) ([]byte, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.closed {
+		return nil, core.ErrDatabaseClosed
+	}
+
+	return getValue(db.values, key)
+}
+
+func (db *Memory) Has(key []byte) (bool, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.closed {
+		return false, core.ErrDatabaseClosed
+	}
+
+	_, ok := db.values[string(key)]
+
+	return ok, nil
+}
+
+// NewIterator walks the keys as they were when it was created.
+func (db *Memory) NewIterator(prefix []byte) Iterator {
+	snapshot, err := db.NewSnapshot()
+	if err != nil {
+		return &memoryIterator{pos: -1, err: err}
+	}
+
+	return snapshot.NewIterator(prefix)
+}
+
+func (db *Memory) Put(key, value []byte) error {
+	batch := db.NewBatch()
+	batch.Put(key, value)
+
+	return db.Write(batch)
+}
+
+func (db *Memory) Delete(key []byte) error {
+	batch := db.NewBatch()
+	batch.Delete(key)
+
+	return db.Write(batch)
+}
+
+func (db *Memory) NewBatch() Batch {
+	return &memoryBatch{db: db}
+}
+
+func (db *Memory) Write(batch Batch) error {
+	b, ok := batch.(*memoryBatch)
+	if !ok || b.db != db {
+		return core.ErrForeignBatch
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.closed {
+		return core.ErrDatabaseClosed
+	}
+
+	// Snapshots keep the map they were taken from, the first write after
+	// one goes to a copy of it.
+	if db.shared {
+		values := make(map[string][]byte, len(db.values))
+		for key, value := range db.values {
+			values[key] = value
+		}
+
+		db.values = values
+		db.shared = false
+	}
+
+	for _, op := range b.ops {
+		if op.delete {
+			delete(db.values, op.key)
+		} else {
+			db.values[op.key] = op.value
+		}
+	}
+
+	return nil
+}
+
+func (db *Memory) NewSnapshot() (Snapshot, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.closed {
+		return nil, core.ErrDatabaseClosed
+	}
+
+	db.shared = true
+
+	return &memorySnapshot{values: db.values}, nil
+}
+
+func (db *Memory) Close() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.closed = true
+	db.values = nil
+
+	return nil
+}
+
+func (b *memoryBatch) Put(key, value []byte) {
+	b.ops = append(b.ops, memoryOp{key: string(key), value: copyBytes(value)})
+}
+
+func (b *memoryBatch) Delete(key []byte) {
+	b.ops = append(b.ops, memoryOp{key: string(key), delete: true})
+}
+
+func (b *memoryBatch) Len() int {
+	return len(b.ops)
+}
+
+func (b *memoryBatch) Reset() {
+	b.ops = nil
+}
+
+func (s *memorySnapshot) Get(key []byte) ([]byte, error) {
+	return getValue(s.values, key)
+}
+
+func (s *memorySnapshot) Has(key []byte) (bool, error) {
+	_, ok := s.values[string(key)]
+
+	return ok, nil
+}
+
+func (s *memorySnapshot) NewIterator(prefix []byte) Iterator {
+	return newMemoryIterator(s.values, prefix)
+}
+
+func (s *memorySnapshot) Release() {}
+
+func (iter *memoryIterator) Next() bool {
+	if iter.pos < len(iter.keys) {
+		iter.pos++
+	}
+
+	return iter.pos < len(iter.keys)
+}
+
+func (iter *memoryIterator) Key() []byte {
+	if iter.pos < 0 || iter.pos >= len(iter.keys) {
+		return nil
+	}
+
+	return []byte(iter.keys[iter.pos])
+}
+
+func (iter *memoryIterator) Value() []byte {
+	if iter.pos < 0 || iter.pos >= len(iter.keys) {
+		return nil
+	}
+
+	return copyBytes(iter.values[iter.keys[iter.pos]])
+}
+
+func (iter *memoryIterator) Release() {
+	iter.keys = nil
+	iter.values = nil
+}
+
+func (iter *memoryIterator) Error() error {
+	return iter.err
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// testDatabases are the backends every test runs against, so the memory one
+// keeps behaving like LevelDB.
+func testDatabases(t *testing.T) map[string]Database {
+	ldb, err := OpenLevelDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { ldb.Close() })
+
+	return map[string]Database{"memory": NewMemory(), "leveldb": ldb}
+}
+
+func iterate(iter Iterator) string {
+	var pairs []string
+
+	for iter.Next() {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", iter.Key(), iter.Value()))
+	}
+	iter.Release()
+
+	return fmt.Sprint(pairs)
+}
+
+func TestGetPutDelete(t *testing.T) {
+	for name, db := range testDatabases(t) {
+		if _, err := db.Get([]byte("key")); err != core.ErrKeyNotFound {
+			t.Errorf("%s: missing key: %v", name, err)
+		}
+
+		value := []byte("value")
+		if err := db.Put([]byte("key"), value); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// The database keeps its own copy of values.
+		value[0] = 'V'
+
+		got, err := db.Get([]byte("key"))
+		if err != nil || string(got) != "value" {
+			t.Errorf("%s: got %q, %v", name, got, err)
+		}
+
+		got[0] = 'V'
+		if got, _ := db.Get([]byte("key")); string(got) != "value" {
+			t.Errorf("%s: returned value shares memory: %q", name, got)
+		}
+
+		if has, err := db.Has([]byte("key")); !has || err != nil {
+			t.Errorf("%s: has %t, %v", name, has, err)
+		}
+
+		if err := db.Delete([]byte("key")); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if has, _ := db.Has([]byte("key")); has {
+			t.Errorf("%s: deleted key is there", name)
+		}
+
+		if err := db.Delete([]byte("key")); err != nil {
+			t.Errorf("%s: deleting a missing key: %v", name, err)
+		}
+	}
+}
+
+func TestIterator(t *testing.T) {
+	for name, db := range testDatabases(t) {
+		for _, key := range []string{"b-2", "a-1", "b-1", "b", "c-1", "b-10"} {
+			if err := db.Put([]byte(key), []byte("v"+key)); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+
+		tests := []struct {
+			prefix string
+			pairs  string
+		}{
+			{"b-", "[b-1=vb-1 b-10=vb-10 b-2=vb-2]"},
+			{"b", "[b=vb b-1=vb-1 b-10=vb-10 b-2=vb-2]"},
+			{"", "[a-1=va-1 b=vb b-1=vb-1 b-10=vb-10 b-2=vb-2 c-1=vc-1]"},
+			{"d", "[]"},
+		}
+
+		for _, test := range tests {
+			if pairs := iterate(db.NewIterator([]byte(test.prefix))); pairs != test.pairs {
+				t.Errorf("%s prefix %q: %s, want %s", name, test.prefix, pairs, test.pairs)
+			}
+		}
+
+		iter := db.NewIterator([]byte("b-"))
+		db.Put([]byte("b-3"), []byte("vb-3"))
+		db.Delete([]byte("b-1"))
+
+		if pairs := iterate(iter); pairs != "[b-1=vb-1 b-10=vb-10 b-2=vb-2]" {
+			t.Errorf("%s: iterator sees later writes: %s", name, pairs)
+		}
+
+		if iter.Error() != nil {
+			t.Errorf("%s: %v", name, iter.Error())
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	for name, db := range testDatabases(t) {
+		db.Put([]byte("deleted"), []byte("v"))
+
+		batch := db.NewBatch()
+		batch.Put([]byte("a"), []byte("1"))
+		batch.Put([]byte("b"), []byte("2"))
+		batch.Delete([]byte("deleted"))
+		batch.Put([]byte("a"), []byte("3"))
+
+		if batch.Len() != 4 {
+			t.Errorf("%s: batch of %d operations", name, batch.Len())
+		}
+
+		if has, _ := db.Has([]byte("a")); has {
+			t.Errorf("%s: batch applied before Write", name)
+		}
+
+		if err := db.Write(batch); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if pairs := iterate(db.NewIterator(nil)); pairs != "[a=3 b=2]" {
+			t.Errorf("%s: %s after the batch", name, pairs)
+		}
+
+		batch.Reset()
+		if batch.Len() != 0 {
+			t.Errorf("%s: reset batch of %d operations", name, batch.Len())
+		}
+	}
+
+	databases := testDatabases(t)
+	if err := databases["memory"].Write(databases["leveldb"].NewBatch()); err != core.ErrForeignBatch {
+		t.Errorf("memory wrote a LevelDB batch: %v", err)
+	}
+	if err := databases["leveldb"].Write(databases["memory"].NewBatch()); err != core.ErrForeignBatch {
+		t.Errorf("LevelDB wrote a memory batch: %v", err)
+	}
+	if err := NewMemory().Write(databases["memory"].NewBatch()); err != core.ErrForeignBatch {
+		t.Errorf("memory wrote the batch of another memory database: %v", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	for name, db := range testDatabases(t) {
+		db.Put([]byte("kept"), []byte("1"))
+		db.Put([]byte("changed"), []byte("1"))
+		db.Put([]byte("deleted"), []byte("1"))
+
+		snapshot, err := db.NewSnapshot()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		db.Put([]byte("changed"), []byte("2"))
+		db.Put([]byte("added"), []byte("2"))
+		db.Delete([]byte("deleted"))
+
+		if pairs := iterate(snapshot.NewIterator(nil)); pairs != "[changed=1 deleted=1 kept=1]" {
+			t.Errorf("%s: snapshot has %s", name, pairs)
+		}
+
+		if value, err := snapshot.Get([]byte("changed")); err != nil || !bytes.Equal(value, []byte("1")) {
+			t.Errorf("%s: snapshot value %q, %v", name, value, err)
+		}
+
+		if has, _ := snapshot.Has([]byte("added")); has {
+			t.Errorf("%s: snapshot has a later key", name)
+		}
+
+		if _, err := snapshot.Get([]byte("added")); err != core.ErrKeyNotFound {
+			t.Errorf("%s: later key in the snapshot: %v", name, err)
+		}
+
+		if pairs := iterate(db.NewIterator(nil)); pairs != "[added=2 changed=2 kept=1]" {
+			t.Errorf("%s: database has %s", name, pairs)
+		}
+
+		snapshot.Release()
+	}
+}
+
+func TestMemoryClose(t *testing.T) {
+	db := NewMemory()
+	db.Put([]byte("key"), []byte("value"))
+
+	snapshot, _ := db.NewSnapshot()
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Get([]byte("key")); err != core.ErrDatabaseClosed {
+		t.Errorf("get: %v", err)
+	}
+
+	if _, err := db.Has([]byte("key")); err != core.ErrDatabaseClosed {
+		t.Errorf("has: %v", err)
+	}
+
+	if err := db.Put([]byte("key"), []byte("value")); err != core.ErrDatabaseClosed {
+		t.Errorf("put: %v", err)
+	}
+
+	if _, err := db.NewSnapshot(); err != core.ErrDatabaseClosed {
+		t.Errorf("snapshot: %v", err)
+	}
+
+	iter := db.NewIterator(nil)
+	if iter.Next() || iter.Error() != core.ErrDatabaseClosed {
+		t.Errorf("iterator: %v", iter.Error())
+	}
+
+	// Snapshots taken before outlive the database.
+	if value, err := snapshot.Get([]byte("key")); err != nil || string(value) != "value" {
+		t.Errorf("snapshot after close: %q, %v", value, err)
+	}
+}
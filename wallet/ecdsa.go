@@ -0,0 +1,161 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+const (
+	// PublicKeyLength is the size of a compressed SEC1 public key.
+	PublicKeyLength = 33
+
+	// Legacy keys are X and Y unpadded, up to 64 bytes.
+	legacyPublicKeyLength = 64
+
+	// ecdsaSignatureLength fits r and s padded to 32 bytes each.
+	ecdsaSignatureLength = 64
+	ecdsaScalarLength    = 32
+)
+
+var (
+	curveOrder = elliptic.P256().Params().N
+	halfOrder  = new(big.Int).Rsh(curveOrder, 1)
+)
+
+type ecdsaSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+type ecdsaVerifier struct {
+	key *ecdsa.PublicKey
+}
+
+func generateECDSAKey() ([]byte, []byte, error) {
+	curve := elliptic.P256()
+
+	private, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	public := elliptic.MarshalCompressed(curve, private.X, private.Y)
+
+	return private.D.FillBytes(make([]byte, ecdsaScalarLength)), public, nil
+}
+
+func newECDSASigner(privateKey []byte) (Signer, error) {
+	curve := elliptic.P256()
+	d := new(big.Int).SetBytes(privateKey)
+
+	if len(privateKey) != ecdsaScalarLength || d.Sign() == 0 || d.Cmp(curveOrder) >= 0 {
+		return nil, core.ErrInvalidPrivateKey
+	}
+
+	key := &ecdsa.PrivateKey{D: d}
+	key.Curve = curve
+	key.X, key.Y = curve.ScalarBaseMult(privateKey)
+
+	return ecdsaSigner{key}, nil
+}
+
+func (signer ecdsaSigner) Algorithm() Algorithm {
+	return ECDSAP256
+}
+
+// Sign uses a low s. Both s and n - s verify, only the low one is accepted.
+func (signer ecdsaSigner) Sign(hash []byte) []byte {
+	r, s, err := ecdsa.Sign(rand.Reader, signer.key, hash)
+	core.Handle(err)
+
+	if s.Cmp(halfOrder) > 0 {
+		s.Sub(curveOrder, s)
+	}
+
+	signature := make([]byte, ecdsaSignatureLength)
+	r.FillBytes(signature[:ecdsaScalarLength])
+	s.FillBytes(signature[ecdsaScalarLength:])
+
+	return signature
+}
+
+func parseECDSAKey(pubKey []byte) (Verifier, error) {
+	var x, y *big.Int
+
+	curve := elliptic.P256()
+
+	if len(pubKey) == PublicKeyLength {
+		x, y = elliptic.UnmarshalCompressed(curve, pubKey)
+	} else {
+		x, y = splitLegacyKey(pubKey)
+	}
+
+	if x == nil {
+		return nil, core.ErrInvalidPublicKey
+	}
+
+	return ecdsaVerifier{&ecdsa.PublicKey{Curve: curve, X: x, Y: y}}, nil
+}
+
+// splitLegacyKey reads the X and Y encoding of legacy wallets. They didn't
+// pad the coordinates, so when one has leading zero bytes the key is shorter
+// and the split is the one that lands on the curve.
+func splitLegacyKey(pubKey []byte) (*big.Int, *big.Int) {
+	curve := elliptic.P256()
+
+	for xLen := ecdsaScalarLength; xLen >= len(pubKey)-ecdsaScalarLength; xLen-- {
+		x := new(big.Int).SetBytes(pubKey[:xLen])
+		y := new(big.Int).SetBytes(pubKey[xLen:])
+
+		if curve.IsOnCurve(x, y) {
+			return x, y
+		}
+	}
+
+	return nil, nil
+}
+
+// legacyPublicKey is the public key of D the way legacy wallets encoded it,
+// which their addresses hash.
+func legacyPublicKey(privateKey []byte) []byte {
+	x, y := elliptic.P256().ScalarBaseMult(privateKey)
+
+	return append(x.Bytes(), y.Bytes()...)
+}
+
+func (verifier ecdsaVerifier) Algorithm() Algorithm {
+	return ECDSAP256
+}
+
+func (verifier ecdsaVerifier) CheckEncoding(signature []byte) error {
+	_, _, err := parseECDSASignature(signature)
+
+	return err
+}
+
+func (verifier ecdsaVerifier) Verify(hash, signature []byte) bool {
+	r, s, err := parseECDSASignature(signature)
+	if err != nil {
+		return false
+	}
+
+	return ecdsa.Verify(verifier.key, hash, r, s)
+}
+
+func parseECDSASignature(signature []byte) (*big.Int, *big.Int, error) {
+	if len(signature) != ecdsaSignatureLength {
+		return nil, nil, core.ErrNonCanonicalSignature
+	}
+
+	r := new(big.Int).SetBytes(signature[:ecdsaScalarLength])
+	s := new(big.Int).SetBytes(signature[ecdsaScalarLength:])
+
+	if r.Sign() == 0 || r.Cmp(curveOrder) >= 0 || s.Sign() == 0 || s.Cmp(halfOrder) > 0 {
+		return nil, nil, core.ErrNonCanonicalSignature
+	}
+
+	return r, s, nil
+}
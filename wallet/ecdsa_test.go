@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+// legacyTestKeys finds private keys whose legacy public key is the given
+// length: X or Y with leading zero bytes make it shorter than 64 bytes.
+func legacyTestKeys(t *testing.T, lengths ...int) map[int][]byte {
+	keys := make(map[int][]byte)
+
+	for tries := 0; len(keys) < len(lengths); tries++ {
+		if tries > 100000 {
+			t.Fatalf("found keys of lengths %v only", keys)
+		}
+
+		privateKey, _, err := GenerateKey(ECDSAP256)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		length := len(legacyPublicKey(privateKey))
+		for _, want := range lengths {
+			if length == want && keys[length] == nil {
+				keys[length] = privateKey
+			}
+		}
+	}
+
+	return keys
+}
+
+func TestLegacyPublicKey(t *testing.T) {
+	hash := sha256.Sum256([]byte("message"))
+
+	for length, privateKey := range legacyTestKeys(t, 64, 63) {
+		pubKey := legacyPublicKey(privateKey)
+
+		verifier, err := ParsePublicKey(pubKey)
+		if err != nil {
+			t.Fatalf("%d byte key: %v", length, err)
+		}
+
+		signer, err := NewSigner(ECDSAP256, privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !verifier.Verify(hash[:], signer.Sign(hash[:])) {
+			t.Errorf("%d byte key: signature does not verify", length)
+		}
+	}
+}
+
+func TestParsePublicKeyLength(t *testing.T) {
+	privateKey, pubKey, err := GenerateKey(ECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := legacyPublicKey(privateKey)
+
+	tests := []struct {
+		name   string
+		pubKey []byte
+		ok     bool
+	}{
+		{"compressed", pubKey, true},
+		{"truncated compressed", pubKey[:PublicKeyLength-1], false},
+		{"legacy", legacy, true},
+		{"legacy with an extra byte", append(legacy[:len(legacy):len(legacy)], 0), false},
+		{"legacy off the curve", append([]byte{legacy[0] ^ 1}, legacy[1:]...), false},
+		{"empty", nil, false},
+	}
+
+	for _, test := range tests {
+		if _, err := ParsePublicKey(test.pubKey); (err == nil) != test.ok {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+}
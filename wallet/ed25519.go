@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// ed25519KeyLength is the algorithm identifier followed by the 32 byte key.
+const ed25519KeyLength = 1 + ed25519.PublicKeySize
+
+type ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+type ed25519Verifier struct {
+	key ed25519.PublicKey
+}
+
+func encodeEd25519Key(key ed25519.PublicKey) []byte {
+	return append([]byte{byte(Ed25519)}, key...)
+}
+
+// generateEd25519Key keeps the seed as the private key.
+func generateEd25519Key() ([]byte, []byte, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return private.Seed(), encodeEd25519Key(public), nil
+}
+
+func newEd25519Signer(seed []byte) (Signer, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, core.ErrInvalidPrivateKey
+	}
+
+	return ed25519Signer{ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+func (signer ed25519Signer) Algorithm() Algorithm {
+	return Ed25519
+}
+
+func (signer ed25519Signer) Sign(hash []byte) []byte {
+	return ed25519.Sign(signer.key, hash)
+}
+
+func parseEd25519Key(pubKey []byte) (Verifier, error) {
+	return ed25519Verifier{ed25519.PublicKey(pubKey[1:])}, nil
+}
+
+func (verifier ed25519Verifier) Algorithm() Algorithm {
+	return Ed25519
+}
+
+// CheckEncoding only checks the size, Verify already rejects a non canonical
+// s.
+func (verifier ed25519Verifier) CheckEncoding(signature []byte) error {
+	if len(signature) != ed25519.SignatureSize {
+		return core.ErrNonCanonicalSignature
+	}
+
+	return nil
+}
+
+func (verifier ed25519Verifier) Verify(hash, signature []byte) bool {
+	return len(signature) == ed25519.SignatureSize && ed25519.Verify(verifier.key, hash, signature)
+}
@@ -0,0 +1,100 @@
+package wallet
+
+import (
+	"strings"
+
+	"github.com/wilmacedo/willchain-go/core"
+)
+
+// Algorithm identifies a signature scheme in wallet files and public keys.
+type Algorithm byte
+
+const (
+	ECDSAP256 Algorithm = 0x01
+	Ed25519   Algorithm = 0xed
+
+	DefaultAlgorithm = ECDSAP256
+)
+
+var algorithmNames = map[Algorithm]string{
+	ECDSAP256: "ecdsa-p256",
+	Ed25519:   "ed25519",
+}
+
+// Signer signs hashes with a private key of its algorithm.
+type Signer interface {
+	Algorithm() Algorithm
+	Sign(hash []byte) []byte
+}
+
+// Verifier checks signatures against the public key it was parsed from.
+// CheckEncoding rejects signatures that are not in the one encoding the
+// scheme produces, so they can't be altered without invalidating them.
+type Verifier interface {
+	Algorithm() Algorithm
+	CheckEncoding(signature []byte) error
+	Verify(hash, signature []byte) bool
+}
+
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for algorithm, algorithmName := range algorithmNames {
+		if strings.ToLower(name) == algorithmName {
+			return algorithm, nil
+		}
+	}
+
+	return 0, core.ErrUnknownAlgorithm
+}
+
+func (algorithm Algorithm) String() string {
+	return algorithmNames[algorithm]
+}
+
+// AddressVersion is the version of the addresses of keys of the algorithm.
+// Both pay to the same public key hash script.
+func (algorithm Algorithm) AddressVersion() byte {
+	if algorithm == Ed25519 {
+		return Ed25519PubKeyHashVersion
+	}
+
+	return PubKeyHashVersion
+}
+
+// GenerateKey returns a new private key and its encoded public key.
+func GenerateKey(algorithm Algorithm) ([]byte, []byte, error) {
+	switch algorithm {
+	case ECDSAP256:
+		return generateECDSAKey()
+	case Ed25519:
+		return generateEd25519Key()
+	}
+
+	return nil, nil, core.ErrUnknownAlgorithm
+}
+
+func NewSigner(algorithm Algorithm, privateKey []byte) (Signer, error) {
+	switch algorithm {
+	case ECDSAP256:
+		return newECDSASigner(privateKey)
+	case Ed25519:
+		return newEd25519Signer(privateKey)
+	}
+
+	return nil, core.ErrUnknownAlgorithm
+}
+
+// ParsePublicKey finds the algorithm of an encoded public key: compressed
+// SEC1 keys start with 0x02 or 0x03, Ed25519 keys with their algorithm
+// identifier. Wallets made before compressed keys hold the X and Y
+// encoding, up to 64 bytes as it wasn't padded, which is still accepted so
+// the results paid to them stay spendable.
+func ParsePublicKey(pubKey []byte) (Verifier, error) {
+	switch {
+	case len(pubKey) == ed25519KeyLength && pubKey[0] == byte(Ed25519):
+		return parseEd25519Key(pubKey)
+	case len(pubKey) == PublicKeyLength, len(pubKey) > PublicKeyLength && len(pubKey) <= legacyPublicKeyLength:
+		return parseECDSAKey(pubKey)
+	}
+
+	return nil, core.ErrInvalidPublicKey
+}
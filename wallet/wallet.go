@@ -2,9 +2,6 @@ package wallet
 
 import (
 	"bytes"
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/sha256"
 
 	"github.com/wilmacedo/willchain-go/core"
@@ -13,19 +10,44 @@ import (
 )
 
 const (
-	ChecksumLength = 4
-	version        = byte(0x00) // hex representation of zero number
+	ChecksumLength           = 4
+	PubKeyHashVersion        = byte(0x00) // hex representation of zero number
+	ScriptHashVersion        = byte(0x05)
+	Ed25519PubKeyHashVersion = byte(0x21)
 )
 
 type Wallet struct {
-	PrivateKey ecdsa.PrivateKey
+	Algorithm  Algorithm
+	PrivateKey []byte
 	PublicKey  []byte
 }
 
 func (wallet Wallet) Address() []byte {
 	pubHash := PublicKeyHash(wallet.PublicKey)
 
-	versionedHash := append([]byte{version}, pubHash...)
+	return EncodeAddress(wallet.Algorithm.AddressVersion(), pubHash)
+}
+
+func (wallet Wallet) Signer() Signer {
+	signer, err := NewSigner(wallet.Algorithm, wallet.PrivateKey)
+	core.Handle(err)
+
+	return signer
+}
+
+// ScriptAddress is the pay to script hash address of a redeem script.
+func ScriptAddress(redeemScript []byte) []byte {
+	return EncodeAddress(ScriptHashVersion, PublicKeyHash(redeemScript))
+}
+
+// IsPubKeyHashVersion tells if addresses of version pay to a public key hash,
+// whatever the algorithm of the key.
+func IsPubKeyHashVersion(version byte) bool {
+	return version == PubKeyHashVersion || version == Ed25519PubKeyHashVersion
+}
+
+func EncodeAddress(version byte, hash []byte) []byte {
+	versionedHash := append([]byte{version}, hash...)
 	checksum := Checksum(versionedHash)
 
 	fullHash := append(versionedHash, checksum...)
@@ -34,6 +56,17 @@ func (wallet Wallet) Address() []byte {
 	return address
 }
 
+// ParseAddress returns the version byte and the hash an address encodes.
+func ParseAddress(address string) (byte, []byte, error) {
+	if !ValidateAddress(address) {
+		return 0, nil, core.ErrInvalidAddress
+	}
+
+	decoded := utils.Base58Decode([]byte(address))
+
+	return decoded[0], decoded[1 : len(decoded)-ChecksumLength], nil
+}
+
 func ValidateAddress(address string) bool {
 	pubKeyHash := utils.Base58Decode([]byte(address))
 	actualChecksum := pubKeyHash[len(pubKeyHash)-ChecksumLength:]
@@ -44,24 +77,19 @@ func ValidateAddress(address string) bool {
 	return bytes.Equal(actualChecksum, targetChecksum)
 }
 
-func NewKeyPair() (ecdsa.PrivateKey, []byte) {
-	curve := elliptic.P256()
-
-	private, err := ecdsa.GenerateKey(curve, rand.Reader)
-	core.Handle(err)
-
-	public := append(private.PublicKey.X.Bytes(), private.PublicKey.Y.Bytes()...)
-	return *private, public
-}
+func MakeWallet(algorithm Algorithm) (*Wallet, error) {
+	private, public, err := GenerateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
 
-func MakeWallet() *Wallet {
-	private, public := NewKeyPair()
 	wallet := &Wallet{
+		Algorithm:  algorithm,
 		PublicKey:  public,
 		PrivateKey: private,
 	}
 
-	return wallet
+	return wallet, nil
 }
 
 func PublicKeyHash(pubKey []byte) []byte {
@@ -2,38 +2,75 @@ package wallet
 
 import (
 	"bytes"
-	"crypto/elliptic"
 	"encoding/gob"
 	"io/ioutil"
+	"math/big"
 	"os"
 
+	"github.com/wilmacedo/willchain-go/config"
 	"github.com/wilmacedo/willchain-go/core"
 )
 
-const walletFile = "./tmp/wallets.data"
+const walletFile = "wallets.data"
 
 type Wallets struct {
 	Wallets map[string]*Wallet
+	Scripts map[string][]byte
 }
 
 func CreateWallets() (*Wallets, error) {
 	wallets := Wallets{}
 	wallets.Wallets = make(map[string]*Wallet)
+	wallets.Scripts = make(map[string][]byte)
 
 	err := wallets.LoadFile()
 
 	return &wallets, err
 }
 
-func (ws *Wallets) AddWallet() string {
-	wallet := MakeWallet()
+func (ws *Wallets) AddWallet(algorithm Algorithm) (string, error) {
+	wallet, err := MakeWallet(algorithm)
+	if err != nil {
+		return "", err
+	}
+
 	address := string(wallet.Address())
 
 	ws.Wallets[address] = wallet
 
+	return address, nil
+}
+
+// AddScript keeps a redeem script so results paid to its address can be
+// recognized and signed later.
+func (ws *Wallets) AddScript(redeemScript []byte) string {
+	address := string(ScriptAddress(redeemScript))
+
+	ws.Scripts[address] = redeemScript
+
 	return address
 }
 
+func (ws *Wallets) FindScriptByHash(scriptHash []byte) ([]byte, bool) {
+	for _, redeemScript := range ws.Scripts {
+		if bytes.Equal(PublicKeyHash(redeemScript), scriptHash) {
+			return redeemScript, true
+		}
+	}
+
+	return nil, false
+}
+
+func (ws *Wallets) FindByPubKey(pubKey []byte) (Wallet, bool) {
+	for _, w := range ws.Wallets {
+		if bytes.Equal(w.PublicKey, pubKey) {
+			return *w, true
+		}
+	}
+
+	return Wallet{}, false
+}
+
 func (ws *Wallets) GetAllAddresses() []string {
 	var addresses []string
 
@@ -48,27 +85,43 @@ func (ws *Wallets) GetWallet(address string) Wallet {
 	return *ws.Wallets[address]
 }
 
+func (ws *Wallets) FindByPubKeyHash(pubKeyHash []byte) (Wallet, bool) {
+	for _, w := range ws.Wallets {
+		if bytes.Equal(PublicKeyHash(w.PublicKey), pubKeyHash) {
+			return *w, true
+		}
+	}
+
+	return Wallet{}, false
+}
+
 func (ws *Wallets) LoadFile() error {
-	if _, err := os.Stat(walletFile); os.IsNotExist(err) {
+	path := config.Path(walletFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return err
 	}
 
 	var wallets Wallets
 
-	fileContent, err := ioutil.ReadFile(walletFile)
+	fileContent, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
 
-	gob.Register(elliptic.P256())
-
 	decoder := gob.NewDecoder(bytes.NewBuffer(fileContent))
-	err = decoder.Decode(&wallets)
-	if err != nil {
-		return err
+	if err := decoder.Decode(&wallets); err != nil {
+		wallets, err = decodeLegacyWallets(fileContent)
+		if err != nil {
+			return err
+		}
 	}
 
 	ws.Wallets = wallets.Wallets
+	ws.Scripts = wallets.Scripts
+
+	if ws.Scripts == nil {
+		ws.Scripts = make(map[string][]byte)
+	}
 
 	return nil
 }
@@ -76,12 +129,64 @@ func (ws *Wallets) LoadFile() error {
 func (ws *Wallets) SaveFile() {
 	var content bytes.Buffer
 
-	gob.Register(elliptic.P256())
-
 	encoder := gob.NewEncoder(&content)
 	err := encoder.Encode(ws)
 	core.Handle(err)
 
-	err = ioutil.WriteFile(walletFile, content.Bytes(), 0644)
+	core.Handle(config.EnsureDir())
+
+	// The file holds private keys: only its owner may read it, even when it
+	// was created by an older release.
+	path := config.Path(walletFile)
+	err = ioutil.WriteFile(path, content.Bytes(), 0600)
+	core.Handle(err)
+
+	err = os.Chmod(path, 0600)
 	core.Handle(err)
 }
+
+// legacyWallets mirrors files that gob encoded the whole ecdsa.PrivateKey.
+// Only D is kept, the curve was always P-256.
+type legacyWallets struct {
+	Wallets map[string]*legacyWallet
+	Scripts map[string][]byte
+}
+
+type legacyWallet struct {
+	PrivateKey legacyPrivateKey
+	PublicKey  []byte
+}
+
+type legacyPrivateKey struct {
+	D *big.Int
+}
+
+func decodeLegacyWallets(fileContent []byte) (Wallets, error) {
+	var legacy legacyWallets
+
+	decoder := gob.NewDecoder(bytes.NewBuffer(fileContent))
+	if err := decoder.Decode(&legacy); err != nil {
+		return Wallets{}, err
+	}
+
+	wallets := Wallets{
+		Wallets: make(map[string]*Wallet),
+		Scripts: legacy.Scripts,
+	}
+
+	for address, w := range legacy.Wallets {
+		if w.PrivateKey.D == nil {
+			return Wallets{}, core.ErrInvalidPrivateKey
+		}
+
+		privateKey := w.PrivateKey.D.FillBytes(make([]byte, ecdsaScalarLength))
+
+		wallets.Wallets[address] = &Wallet{
+			Algorithm:  ECDSAP256,
+			PrivateKey: privateKey,
+			PublicKey:  legacyPublicKey(privateKey),
+		}
+	}
+
+	return wallets, nil
+}